@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本地清单缓存, 按 SHA-256 保存下载源返回的原始内容, 并按 AppID 记录元数据
+//
+//	<Dir>/objects/<sha256 前两位>/<sha256>   原始 <AppID>.lua
+//	<Dir>/apps/<AppID>.json                   CacheEntry
+type ManifestCache struct {
+	Dir string
+}
+
+// 某个 AppID 最近一次下载的记录
+type CacheEntry struct {
+	AppID          string    `json:"appid"`
+	Source         string    `json:"source"`                    // 下载源地址
+	FetchedAt      time.Time `json:"fetched_at"`                // 最近一次下载或确认未变化的时间
+	ETag           string    `json:"etag,omitempty"`            // 下载源返回的 ETag
+	SHA256         string    `json:"sha256"`                    // 当前内容的哈希
+	Size           int       `json:"size"`                      // 当前内容的字节数
+	PreviousSHA256 string    `json:"previous_sha256,omitempty"` // 内容变化前的哈希, 用于比较版本
+}
+
+// 配置的清单缓存, 未启用时为 nil
+func OpenCache(config *Config) *ManifestCache {
+	if !config.CacheEnabled || config.CacheDir == "" {
+		return nil
+	}
+	return &ManifestCache{Dir: config.CacheDir}
+}
+
+// 内容的 SHA-256 十六进制字符串
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *ManifestCache) objectPath(sum string) string {
+	return filepath.Join(c.Dir, "objects", sum[:2], sum)
+}
+
+func (c *ManifestCache) entryPath(appid string) string {
+	return filepath.Join(c.Dir, "apps", appid+".json")
+}
+
+// 读取 AppID 的缓存记录, 不存在时返回 nil
+func (c *ManifestCache) Entry(appid string) (*CacheEntry, error) {
+	data, err := os.ReadFile(c.entryPath(appid))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, Err("cache.read_failed", err)
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, Err("cache.read_failed", err)
+	}
+	return &entry, nil
+}
+
+// 按哈希读取缓存的内容, 并校验内容未被改动
+func (c *ManifestCache) Object(sum string) ([]byte, error) {
+	if len(sum) < 2 {
+		return nil, Err("cache.bad_object", sum)
+	}
+	data, err := os.ReadFile(c.objectPath(sum))
+	if err != nil {
+		return nil, Err("cache.read_failed", err)
+	}
+	if sha256Hex(data) != sum {
+		return nil, Err("cache.bad_object", sum)
+	}
+	return data, nil
+}
+
+// 保存下载的内容并更新 AppID 的记录, 内容变化时保留之前的哈希
+func (c *ManifestCache) Store(appid, source, etag string, data []byte) (*CacheEntry, error) {
+	sum := sha256Hex(data)
+	// 已有的内容损坏时重新写入
+	if _, err := c.Object(sum); err != nil {
+		if err := writeFileAtomic(c.objectPath(sum), data); err != nil {
+			return nil, err
+		}
+	}
+
+	entry := &CacheEntry{AppID: appid, Source: source, FetchedAt: time.Now(), ETag: etag, SHA256: sum, Size: len(data)}
+	if old, err := c.Entry(appid); err == nil && old != nil {
+		entry.PreviousSHA256 = old.PreviousSHA256
+		if old.SHA256 != sum {
+			entry.PreviousSHA256 = old.SHA256
+		}
+	}
+	if err := c.writeEntry(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// 下载源确认内容未变化, 只更新记录的时间
+func (c *ManifestCache) Touch(entry *CacheEntry) error {
+	entry.FetchedAt = time.Now()
+	return c.writeEntry(entry)
+}
+
+func (c *ManifestCache) writeEntry(entry *CacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return Err("cache.write_failed", err)
+	}
+	return writeFileAtomic(c.entryPath(entry.AppID), data)
+}
+
+// 所有 AppID 的缓存记录, 按 AppID 排序
+func (c *ManifestCache) List() ([]CacheEntry, error) {
+	files, err := os.ReadDir(filepath.Join(c.Dir, "apps"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, Err("cache.read_failed", err)
+	}
+
+	var entries []CacheEntry
+	for _, file := range files {
+		appid, ok := strings.CutSuffix(file.Name(), ".json")
+		if !ok {
+			continue
+		}
+		entry, err := c.Entry(appid)
+		if err != nil {
+			logger.Warn(T("cache.ignored", err), "appid", appid, "step", "cache")
+			continue
+		}
+		if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return appIDLess(entries[i].AppID, entries[j].AppID) })
+	return entries, nil
+}
+
+// 删除超过 maxAge 未更新的记录(maxAge 为 0 时不删除记录), 再删除没有记录引用的内容
+// 返回删除的记录数和内容数
+func (c *ManifestCache) Prune(maxAge time.Duration) (int, int, error) {
+	entries, err := c.List()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removedEntries := 0
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		if maxAge > 0 && time.Since(entry.FetchedAt) > maxAge {
+			if err := os.Remove(c.entryPath(entry.AppID)); err != nil {
+				return removedEntries, 0, Err("cache.remove_failed", err)
+			}
+			removedEntries++
+			continue
+		}
+		referenced[entry.SHA256] = true
+		if entry.PreviousSHA256 != "" {
+			referenced[entry.PreviousSHA256] = true
+		}
+	}
+
+	removedObjects := 0
+	objectsDir := filepath.Join(c.Dir, "objects")
+	err = filepath.WalkDir(objectsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || referenced[d.Name()] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removedObjects++
+		return nil
+	})
+	if err != nil {
+		return removedEntries, removedObjects, Err("cache.remove_failed", err)
+	}
+	return removedEntries, removedObjects, nil
+}
+
+// 删除整个缓存目录
+func (c *ManifestCache) Clear() error {
+	if err := os.RemoveAll(c.Dir); err != nil {
+		return Err("cache.remove_failed", err)
+	}
+	return nil
+}
+
+// 离线模式下从缓存读取清单, 代替从下载源获取
+func LoadCached(ctx context.Context, cache *ManifestCache, APPID string) (*FetchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, Err("cancel.canceled", err)
+	}
+	if cache == nil {
+		return nil, markError(ErrInvalidInput, Err("cache.disabled"))
+	}
+	start := time.Now()
+	entry, err := cache.Entry(APPID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, markError(ErrManifestNotFound, Err("cache.miss", APPID))
+	}
+	data, err := cache.Object(entry.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(T("cache.hit", entry.FetchedAt.Format(time.DateTime), entry.Source), "appid", APPID, "source", entry.Source, "step", "fetch")
+	printDivision()
+	attempts := []SourceAttempt{{Source: "cache", URL: entry.Source, LatencyMS: time.Since(start).Milliseconds()}}
+	return &FetchResult{Data: data, Source: entry.Source, Attempts: attempts}, nil
+}
+
+// cache prune 的结果, JSON 输出模式下输出
+type CachePruneResult struct {
+	Dir            string `json:"dir"`
+	EntriesRemoved int    `json:"entries_removed"` // 删除的记录数
+	ObjectsRemoved int    `json:"objects_removed"` // 删除的内容数
+}
+
+// cache clear 的结果, JSON 输出模式下输出
+type CacheClearResult struct {
+	Dir string `json:"dir"` // 已删除的缓存目录
+}
+
+// 执行 cache 子命令: ls 列出缓存, prune [时长] 清理过期记录及无引用的内容, clear 删除整个缓存
+func RunCacheCommand(config *Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return usageError(Err("cmd.unknown_cache"))
+	}
+	cache := OpenCache(config)
+	if cache == nil {
+		return markError(ErrInvalidInput, Err("cache.disabled"))
+	}
+
+	switch args[0] {
+	case "ls":
+		entries, err := cache.List()
+		if err != nil {
+			return err
+		}
+		if config.Output != OutputText {
+			return writeRecords(out, config.Output, entries)
+		}
+		for _, entry := range entries {
+			fmt.Fprintf(out, "%-10s %s  %8d  %s  %s\n",
+				entry.AppID, entry.SHA256[:min(12, len(entry.SHA256))], entry.Size, entry.FetchedAt.Format(time.DateTime), entry.Source)
+		}
+		fmt.Fprintln(out, T("cache.count", len(entries), cache.Dir))
+		return nil
+	case "prune":
+		var maxAge time.Duration
+		if len(args) > 1 {
+			var err error
+			if maxAge, err = parseAge(args[1]); err != nil {
+				return markError(ErrInvalidInput, err)
+			}
+		}
+		entries, objects, err := cache.Prune(maxAge)
+		if err != nil {
+			return err
+		}
+		if config.Output != OutputText {
+			return writeRecord(out, config.Output, CachePruneResult{Dir: cache.Dir, EntriesRemoved: entries, ObjectsRemoved: objects})
+		}
+		fmt.Fprintln(out, T("cache.pruned", entries, objects))
+		return nil
+	case "clear":
+		if err := cache.Clear(); err != nil {
+			return err
+		}
+		if config.Output != OutputText {
+			return writeRecord(out, config.Output, CacheClearResult{Dir: cache.Dir})
+		}
+		fmt.Fprintln(out, T("cache.cleared", cache.Dir))
+		return nil
+	}
+	return usageError(Err("cmd.unknown_cache"))
+}
+
+// 先写临时文件再替换, 避免中断或并发写入时留下不完整的文件
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Err("fs.mkdir_failed", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return Err("cache.write_failed", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return Err("cache.write_failed", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return Err("cache.write_failed", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return Err("cache.write_failed", err)
+	}
+	return nil
+}
+
+// 解析缓存保留时间, 除 Go 的时长格式外支持以 d 结尾的天数(如 30d)
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, Err("cache.bad_age", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, Err("cache.bad_age", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试时关闭日志与分隔线输出
+func quietLogs(t *testing.T) {
+	t.Helper()
+	oldLogger, oldLevel := logger, consoleLevel
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	consoleLevel = LevelWarn
+	t.Cleanup(func() { logger, consoleLevel = oldLogger, oldLevel })
+}
+
+// 依次保存时记录当前与上一个版本的哈希
+func TestCacheStore(t *testing.T) {
+	cache := &ManifestCache{Dir: t.TempDir()}
+	v1, v2 := []byte("addappid(1)\n"), []byte("addappid(1)\naddappid(2)\n")
+
+	tests := []struct {
+		name         string
+		data         []byte
+		etag         string
+		wantSHA      string
+		wantPrevious string
+	}{
+		{"first version", v1, `"a"`, sha256Hex(v1), ""},
+		{"same content", v1, `"a"`, sha256Hex(v1), ""},
+		{"changed", v2, `"b"`, sha256Hex(v2), sha256Hex(v1)},
+		{"unchanged keeps previous", v2, `"b"`, sha256Hex(v2), sha256Hex(v1)},
+		{"changed back", v1, `"c"`, sha256Hex(v1), sha256Hex(v2)},
+	}
+	for _, tt := range tests {
+		if _, err := cache.Store("1", "https://example.com/1.lua", tt.etag, tt.data); err != nil {
+			t.Fatalf("%s: Store: %v", tt.name, err)
+		}
+		entry, err := cache.Entry("1")
+		if err != nil || entry == nil {
+			t.Fatalf("%s: Entry = %v, %v", tt.name, entry, err)
+		}
+		if entry.SHA256 != tt.wantSHA || entry.PreviousSHA256 != tt.wantPrevious || entry.ETag != tt.etag || entry.Size != len(tt.data) {
+			t.Errorf("%s: entry = %+v, want sha %s previous %q", tt.name, entry, tt.wantSHA[:12], tt.wantPrevious)
+		}
+		if data, err := cache.Object(entry.SHA256); err != nil || string(data) != string(tt.data) {
+			t.Errorf("%s: Object = %q, %v", tt.name, data, err)
+		}
+	}
+
+	if entry, err := cache.Entry("2"); entry != nil || err != nil {
+		t.Errorf("Entry of unknown AppID = %v, %v; want nil, nil", entry, err)
+	}
+}
+
+func TestCacheObjectCorrupted(t *testing.T) {
+	cache := &ManifestCache{Dir: t.TempDir()}
+	entry, err := cache.Store("1", "src", "", []byte("addappid(1)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cache.objectPath(entry.SHA256), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Object(entry.SHA256); err == nil {
+		t.Fatal("Object returned corrupted content")
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	quietLogs(t)
+	tests := []struct {
+		name          string
+		maxAge        time.Duration
+		wantEntries   int
+		wantObjects   int
+		wantRemaining []string // 剩余记录的 AppID
+	}{
+		{"only unreferenced objects", 0, 0, 1, []string{"1", "2"}},
+		{"old entries and their objects", 30 * 24 * time.Hour, 1, 2, []string{"2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &ManifestCache{Dir: t.TempDir()}
+			old, err := cache.Store("1", "src", "", []byte("old"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			old.FetchedAt = time.Now().Add(-60 * 24 * time.Hour)
+			if err := cache.writeEntry(old); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cache.Store("2", "src", "", []byte("v1")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cache.Store("2", "src", "", []byte("v2")); err != nil {
+				t.Fatal(err)
+			}
+			// 没有记录引用的内容
+			orphan := sha256Hex([]byte("orphan"))
+			if err := writeFileAtomic(cache.objectPath(orphan), []byte("orphan")); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, objects, err := cache.Prune(tt.maxAge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entries != tt.wantEntries || objects != tt.wantObjects {
+				t.Errorf("Prune = %d entries, %d objects; want %d, %d", entries, objects, tt.wantEntries, tt.wantObjects)
+			}
+			remaining, _ := cache.List()
+			var ids []string
+			for _, entry := range remaining {
+				ids = append(ids, entry.AppID)
+				// 当前与上一个版本的内容都应保留
+				for _, sum := range []string{entry.SHA256, entry.PreviousSHA256} {
+					if sum == "" {
+						continue
+					}
+					if _, err := cache.Object(sum); err != nil {
+						t.Errorf("object %s of AppID %s removed: %v", sum[:12], entry.AppID, err)
+					}
+				}
+			}
+			if !slices.Equal(ids, tt.wantRemaining) {
+				t.Errorf("remaining entries = %v, want %v", ids, tt.wantRemaining)
+			}
+			if _, err := os.Stat(cache.objectPath(orphan)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("orphan object not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"12h", 12 * time.Hour, false},
+		{"-1d", 0, true},
+		{"-2h", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// 缓存记录来自同一地址且有 ETag 时发送条件请求, 304 时使用缓存的内容
+func TestFetchSourceNotModified(t *testing.T) {
+	quietLogs(t)
+	content := []byte("addappid(123)\n")
+	var conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	source := urlSource{server.URL + "/{appid}.lua"}
+	tests := []struct {
+		name            string
+		prepare         func(cache *ManifestCache)
+		wantConditional int32
+		wantNotModified bool
+	}{
+		{"no cache entry", func(*ManifestCache) {}, 0, false},
+		{"cached with etag", func(cache *ManifestCache) {
+			cache.Store("123", server.URL+"/123.lua", `"v1"`, content)
+		}, 1, true},
+		{"cached from another address", func(cache *ManifestCache) {
+			cache.Store("123", "https://mirror.example/123.lua", `"v1"`, content)
+		}, 0, false},
+		{"cached without etag", func(cache *ManifestCache) {
+			cache.Store("123", server.URL+"/123.lua", "", content)
+		}, 0, false},
+		{"cached object corrupted", func(cache *ManifestCache) {
+			entry, _ := cache.Store("123", server.URL+"/123.lua", `"v1"`, content)
+			os.WriteFile(cache.objectPath(entry.SHA256), []byte("changed"), 0644)
+		}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditional.Store(0)
+			cache := &ManifestCache{Dir: t.TempDir()}
+			tt.prepare(cache)
+			cached, err := cache.Entry("123")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			payload, err := fetchSource(context.Background(), source, "123", cache, cached)
+			if err != nil {
+				t.Fatalf("fetchSource: %v", err)
+			}
+			if string(payload.Data) != string(content) {
+				t.Errorf("data = %q, want %q", payload.Data, content)
+			}
+			if payload.NotModified != tt.wantNotModified {
+				t.Errorf("NotModified = %v, want %v", payload.NotModified, tt.wantNotModified)
+			}
+			if got := conditional.Load(); got != tt.wantConditional {
+				t.Errorf("conditional requests = %d, want %d", got, tt.wantConditional)
+			}
+
+			// 之后的记录指向本次的地址与 ETag, 内容可以读取
+			entry, err := cache.Entry("123")
+			if err != nil || entry == nil || entry.Source != server.URL+"/123.lua" || entry.ETag != `"v1"` {
+				t.Fatalf("entry after fetch = %+v, %v", entry, err)
+			}
+			if _, err := cache.Object(entry.SHA256); err != nil {
+				t.Errorf("cached object unreadable: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadCached(t *testing.T) {
+	quietLogs(t)
+	cache := &ManifestCache{Dir: t.TempDir()}
+	if _, err := LoadCached(context.Background(), cache, "123"); !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("LoadCached on empty cache: err = %v, want ErrManifestNotFound", err)
+	}
+	if _, err := LoadCached(context.Background(), nil, "123"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("LoadCached without cache: err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := cache.Store("123", "src", "", []byte("addappid(123)\n")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := LoadCached(context.Background(), cache, "123")
+	if err != nil || string(result.Data) != "addappid(123)\n" || result.Source != "src" {
+		t.Fatalf("LoadCached = %+v, %v", result, err)
+	}
+}
+
+// 再次保存同样的内容时修复损坏的文件
+func TestCacheStoreRepairsObject(t *testing.T) {
+	cache := &ManifestCache{Dir: t.TempDir()}
+	data := []byte("addappid(1)\n")
+	entry, err := cache.Store("1", "src", "", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cache.objectPath(entry.SHA256), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Store("1", "src", "", data); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cache.Object(entry.SHA256); err != nil || string(got) != string(data) {
+		t.Fatalf("Object after Store = %q, %v", got, err)
+	}
+}
+
+// JSON 输出模式下标准输出只有记录, ndjson 每行一个记录
+func TestRunCacheCommandOutput(t *testing.T) {
+	quietLogs(t)
+	tests := []struct {
+		args      []string
+		output    string
+		wantLines int // ndjson 的记录数, 其余格式不检查
+	}{
+		{[]string{"ls"}, OutputNDJSON, 2},
+		{[]string{"ls"}, OutputJSON, -1},
+		{[]string{"prune"}, OutputNDJSON, 1},
+		{[]string{"prune", "30d"}, OutputJSON, -1},
+		{[]string{"clear"}, OutputNDJSON, 1},
+		{[]string{"clear"}, OutputJSON, -1},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " ")+" "+tt.output, func(t *testing.T) {
+			config := &Config{CacheEnabled: true, CacheDir: t.TempDir(), Output: tt.output}
+			cache := OpenCache(config)
+			for _, appid := range []string{"10", "20"} {
+				if _, err := cache.Store(appid, "src", "", []byte("addappid("+appid+")\n")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out bytes.Buffer
+			if err := RunCacheCommand(config, tt.args, &out); err != nil {
+				t.Fatal(err)
+			}
+			if tt.output == OutputNDJSON {
+				if n := ndjsonLines(t, out.String()); n != tt.wantLines {
+					t.Fatalf("wrote %d records, want %d:\n%s", n, tt.wantLines, out.String())
+				}
+				return
+			}
+			if !json.Valid(out.Bytes()) {
+				t.Fatalf("output is not JSON:\n%s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+// 英文语言包
+var catalogEN = map[string]string{
+	"app.title":            "ManifestHub CLI - next-generation key fetching tool",
+	"app.developer":        "Developer: LANREN",
+	"app.version":          "Version: V1.2",
+	"app.input_closed":     "Input closed, exiting",
+	"app.get_appid_failed": "Failed to get AppID: %v",
+
+	"common.yes": "yes",
+	"common.no":  "no",
+
+	"cmd.unknown_output":   "Unknown output format: %s",
+	"cmd.unknown_index":    "Unknown index subcommand",
+	"cmd.unknown":          "Unknown command: %s",
+	"cmd.output_failed":    "Failed to write result: %w",
+	"cmd.summary_failed":   "%s: failed (%s)\n",
+	"cmd.summary_ok":       "%s: saved to %s, %d DLC added, took %.2fs\n",
+	"cmd.downloads_failed": "%d/%d downloads failed, first error: %w",
+	"cmd.failed":           "Command failed: %v",
+	"cmd.unknown_i18n":     "Unknown i18n subcommand",
+	"cmd.unknown_cache":    "Unknown cache subcommand",
+
+	"flag.output":      "Output format: text / json / ndjson",
+	"flag.log_level":   "Log level: quiet / normal / verbose / debug",
+	"flag.log_file":    "Log file path",
+	"flag.require_key": "Treat a missing DepotKey as a download failure",
+	"flag.extract_all": "When downloaded from the ZIP source, extract the remaining files to <download path>/<AppID>",
+	"flag.offline":     "Serve manifests from the local cache only, without contacting sources",
+	"flag.force":       "Allow offline mode to overwrite downloaded files with the unpatched cached manifest",
+
+	"config.create_failed":    "Failed to create config file: %v, using default path",
+	"config.created":          "Created default config file: config.ini",
+	"config.read_failed":      "Failed to read config file: %v, using default path",
+	"config.abs_failed":       "Failed to resolve path: %v, using it as is",
+	"config.bad_search_mode":  "Unknown search mode: %s, ignored",
+	"config.bad_search_limit": "Invalid searchLimit: %s, ignored",
+	"config.bad_probe":        "Invalid probeResults: %s, ignored",
+	"config.ignored":          "%v, ignored",
+	"config.download_path":    "Download path: %s",
+	"config.write_failed":     "Failed to write config file: %w",
+	"config.bad_value":        "Invalid %s: %s, ignored",
+	"config.bad_size":         "invalid size: %s",
+
+	"log.bad_level":    "Unknown log level: %s (options: quiet/normal/verbose/debug)",
+	"log.open_failed":  "Failed to open log file: %w",
+	"log.setup_failed": "%v, using default log settings",
+	"log.prefix_error": "[ERROR] ",
+	"log.prefix_warn":  "[WARN] ",
+	"log.prefix_debug": "[DEBUG] ",
+
+	"download.start":   "Downloading: %s.lua",
+	"download.sources": "Trying the following sources:",
+	"download.failed":  "Download failed: %v",
+	"download.elapsed": "Elapsed: %.2fs",
+
+	"fetch.try_source":       "Trying source #%d: %s",
+	"fetch.source_failed":    "Source #%d failed: %w",
+	"fetch.source_ok":        "Downloaded from source #%d",
+	"fetch.all_failed":       "All %d sources failed: %v",
+	"fetch.report":           "Source attempts:",
+	"fetch.report_row":       " %s %s | status: %s | latency: %dms | %s",
+	"fetch.report_no_status": "-",
+
+	"http.new_request_failed": "Failed to create request: %w",
+	"http.request_failed":     "Request failed: %w",
+	"http.bad_status":         "Unexpected HTTP status: %d",
+	"http.json_failed":        "Failed to parse JSON: %w",
+	"http.read_failed":        "failed to read response: %w",
+	"http.too_large":          "response exceeds size limit (%d bytes)",
+
+	"zip.trying":            "Trying ZIP source: %s",
+	"zip.retry":             "Retrying Walftech source (attempt %d)...",
+	"zip.bad_status":        "Unexpected status %d (URL: %s)",
+	"zip.idle":              "No progress for %v, download cancelled",
+	"zip.progress_pct":      "\rDownloading: %.2f%% (%d/%d bytes)  %.2f KB/s",
+	"zip.progress":          "\rDownloading: %d bytes  %.2f KB/s",
+	"zip.not_zip":           "Failed to parse ZIP: response is not a valid ZIP file (URL: %s)",
+	"zip.parse_failed":      "Failed to parse ZIP: %w",
+	"zip.open_entry_failed": "Failed to open ZIP entry: %w",
+	"zip.read_entry_failed": "Failed to read ZIP entry: %w",
+	"zip.extracted":         "Extracted %s from Walftech source (%d bytes)",
+	"zip.not_found":         "%s not found in ZIP (URL: %s)",
+	"zip.idle_timeout":      "no data received for too long",
+	"zip.read_error":        "failed to read ZIP data: %w",
+	"zip.will_retry":        "%v, retrying...",
+	"zip.empty":             "ZIP source returned an empty body (URL: %s)",
+	"zip.resuming":          "Resuming download at byte %d",
+	"zip.size_mismatch":     "downloaded size %d does not match the %d reported by the server",
+	"zip.stale_removed":     "Removed stale partial download: %s",
+	"zip.archive_too_large": "ZIP archive too large: %d bytes, limit %d bytes",
+	"zip.too_many_entries":  "ZIP has too many entries: %d, limit %d",
+	"zip.unsafe_name":       "ZIP contains an unsafe path: %s",
+	"zip.entry_too_large":   "ZIP entry %s too large: %d bytes, limit %d bytes",
+	"zip.ratio_too_high":    "ZIP entry %s exceeds compression ratio %g, possible zip bomb",
+	"zip.total_too_large":   "ZIP decompressed size exceeds limit of %d bytes",
+	"zip.extracting":        "Extracting remaining ZIP files to: %s",
+	"zip.extracted_file":    " %s (%d bytes)",
+	"zip.extract_done":      "Extracted %d files to: %s",
+	"zip.extract_failed":    "Failed to extract ZIP: %v",
+	"zip.part_in_use":       "%s is in use by another download, using a separate temporary file without resuming",
+
+	"depotkeys.try_source":          "Trying DepotKey source #%d: %s",
+	"depotkeys.source_failed":       "DepotKey source #%d failed: %w",
+	"depotkeys.source_status":       "DepotKey source #%d returned status %d",
+	"depotkeys.source_parse_failed": "DepotKey source #%d parse failed: %w",
+	"depotkeys.ok":                  "Downloaded depotkeys.json from source #%d (%d entries)",
+	"depotkeys.all_failed":          "All %d DepotKey sources failed: %v",
+	"depotkeys.download_failed":     "Failed to download DepotKeys: %v",
+	"depotkeys.no_key":              "No DepotKey found for AppID %s",
+	"depotkeys.found":               "Found DepotKey for AppID %s: %s",
+	"depotkeys.regex":               "Using regular expression: %s",
+	"depotkeys.match":               "Matched: %s",
+	"depotkeys.patch_target":        "Found addappid(%s) to patch",
+	"depotkeys.replacement":         "Replacing with: %s",
+	"depotkeys.patched":             "DepotKey patched",
+	"depotkeys.no_target":           "No addappid(%s) to patch",
+
+	"fs.mkdir_failed": "Failed to create directory: %w",
+	"fs.save_failed":  "Failed to save file: %w",
+	"fs.open_failed":  "Failed to open file: %w",
+	"fs.saved":        "File saved to: %s (%d bytes)",
+	"fs.read_failed":  "failed to read file: %w",
+
+	"process.commented_line": "Commented out: %s",
+	"process.commented":      "Commented out %d setManifest lines",
+
+	"dlc.start":                "Adding DLCs without depots...",
+	"dlc.failed":               "Failed to add DLCs: %v",
+	"dlc.done":                 "DLCs added",
+	"dlc.main_failed":          "Failed to get DLCs of the main game: %w",
+	"dlc.provider":             "DLC info provider: %s",
+	"dlc.detail_failed":        "Failed to get info for DLC %s: %v",
+	"dlc.keep":                 "keep",
+	"dlc.exclude":              "skip",
+	"dlc.decision":             " [%s] %s %s (%s, provider: %s)",
+	"dlc.filter_summary":       "DLC filter results (kept %d of %d):",
+	"dlc.none":                 "No DLCs without depots found",
+	"dlc.all_exist":            "All DLCs without depots are already in the unlock file",
+	"dlc.write_failed":         "Failed to write DLC %s: %v",
+	"dlc.added":                "Added DLC: %s",
+	"dlc.depots_unknown":       "%s does not report depots for DLC %s, skipping it because it may have depots (set dlcUnknownDepots = add to add it)",
+	"dlc.depots_unknown_added": "%s does not report depots for DLC %s, treating it as a DLC without depots (dlcUnknownDepots = add)",
+
+	"filter.bad_regex":          "Invalid DLC name regular expression: %w",
+	"filter.no_rules":           "no filter rules",
+	"filter.denied":             "in deny list",
+	"filter.allowed":            "in allow list",
+	"filter.not_allowed":        "not in allow list",
+	"filter.type_excluded":      "type %s excluded",
+	"filter.name_excluded":      "name matches %s",
+	"filter.passed":             "passed filters",
+	"filter.bad_unknown_depots": "dlcUnknownDepots must be add or skip: %s",
+
+	"provider.none":        "No app info provider configured",
+	"provider.unknown":     "Unknown app info provider: %s",
+	"provider.not_found":   "No info found for AppID %s",
+	"provider.dlc_string":  "DLC field is a string: %s",
+	"provider.dlc_type":    "Unexpected DLC field type: %T",
+	"provider.depots_type": "depots field is not a map: %T",
+
+	"index.downloading":       "Downloading app list: %s",
+	"index.download_failed":   "Failed to download app list: %w",
+	"index.bad_status":        "App list API returned status %d",
+	"index.parse_failed":      "Failed to parse app list: %w",
+	"index.empty":             "App list is empty",
+	"index.marshal_failed":    "Failed to serialize index: %w",
+	"index.save_failed":       "Failed to save index: %w",
+	"index.updated":           "Local index updated: %s (%d entries)",
+	"index.missing":           "Local index not found: %s, run \"index update\" first",
+	"index.read_failed":       "Failed to read local index: %w",
+	"index.load_parse_failed": "Failed to parse local index: %w",
+	"index.searching":         "Searching local index: %s (updated %s)",
+
+	"search.type_dlc":       "DLC",
+	"search.type_game":      "Game",
+	"search.type_tool":      "Tool",
+	"search.type_music":     "Music",
+	"search.type_video":     "Video",
+	"search.type_demo":      "Demo",
+	"search.probing":        "Checking manifest availability for results...",
+	"search.known_failed":   "Failed to get ManifestHub coverage: %v",
+	"search.row":            " %d. [%s] %-30s | AppID: %-8d | Type: %-5s | In ManifestHub: %s\n",
+	"search.empty_name":     "Game name must not be empty",
+	"search.online_failed":  "Online search failed: %v, falling back to local index",
+	"search.no_match":       "No games matching '%s'",
+	"search.found":          "Found %d matching games:",
+	"search.searching":      "Searching for: %s",
+	"search.request_url":    "Request URL: %s",
+	"search.request_failed": "Search request failed: %w",
+	"search.bad_status":     "Search API returned status %d",
+	"search.parse_failed":   "Failed to parse search results: %w (the API format may have changed)",
+	"search.failed":         "Search failed: %w",
+	"search.none":           "No matching games found, please try again",
+
+	"input.read_failed":        "Failed to read input: %w",
+	"input.empty":              "Input must not be empty",
+	"input.bad_appid":          "Extracted AppID is not a valid number: %s",
+	"input.extracted":          "Extracted AppID from input: %d",
+	"input.numeric":            "Input is a numeric AppID: %d",
+	"input.invalid":            "Invalid format, enter a game name, Steam URL or numeric AppID",
+	"input.prompt":             "Enter game name/AppID/Steam link/SteamDB link: ",
+	"input.fallback_search":    "Could not extract an AppID, searching by name '%s'...",
+	"input.select":             "Enter the number of the game to download: ",
+	"input.select_more":        "Enter the number of the game to download (m for more, %d remaining): ",
+	"input.select_read_failed": "Failed to read selection: %w",
+	"input.select_nan":         "Selection must be a number between 1 and %d",
+	"input.select_range":       "Invalid selection, choose a number between 1 and %d",
+	"input.select_unavailable": "%s (AppID: %d) has no manifest in any source, the download will likely fail",
+	"input.selected":           "Selected: %s (AppID: %d)",
+	"input.select_unknown":     "Could not confirm whether %s (AppID: %d) has a manifest, trying to download anyway",
+
+	"i18n.bad_language":  "Unknown language: %s",
+	"i18n.missing":       "Catalog %s is missing: %s",
+	"i18n.missing_count": "%d translations missing",
+	"i18n.ok":            "All catalogs complete (%d keys)",
+
+	"error.manifest_not_found": "manifest not found",
+	"error.all_sources_failed": "all sources failed",
+	"error.network":            "network error",
+	"error.invalid_zip":        "invalid ZIP file",
+	"error.no_depot_key":       "no DepotKey available",
+	"error.invalid_input":      "invalid input",
+
+	"cancel.canceled":          "operation canceled: %w",
+	"cancel.download_canceled": "Download of AppID %s canceled",
+	"cancel.terminated":        "Received termination signal, exiting",
+	"cancel.search_canceled":   "Search canceled",
+
+	"local.start":       "Processing local file: %s",
+	"local.appid":       "Using AppID %s (from %s)",
+	"local.reading":     "Reading local file: %s",
+	"local.read_failed": "failed to read local file: %w",
+	"local.too_large":   "local file too large: %d bytes, limit %d bytes",
+	"local.no_appid":    "cannot determine the AppID from file name: %s, rename it to <AppID>.zip or <AppID>.lua",
+
+	"git.not_repo":      "%s is not a usable git repository: %w",
+	"git.not_installed": "git command not found: %w",
+	"git.no_file":       "%s has no %s.lua",
+	"git.read":          "Read from %s in the local repository",
+	"git.not_found":     "local repository %[2]s has no branch for AppID %[1]s",
+
+	"source.unknown":       "unrecognized source %q (expected an http(s) URL template or a git:, dir: or zip: prefix)",
+	"source.dir_not_found": "directory %[2]s has no manifest for AppID %[1]s",
+
+	"cache.read_failed":   "failed to read cache: %w",
+	"cache.write_failed":  "failed to write cache: %w",
+	"cache.remove_failed": "failed to remove cache: %w",
+	"cache.bad_object":    "cached object %s is corrupted",
+	"cache.disabled":      "manifest cache is disabled (cache = false in config)",
+	"cache.miss":          "no cached manifest for AppID %s",
+	"cache.hit":           "Using cached manifest (fetched %s from %s)",
+	"cache.not_modified":  "Manifest not modified, using cache (%s)",
+	"cache.ignored":       "Ignored cache error: %v",
+	"cache.bad_age":       "invalid duration: %s (e.g. 30d, 12h)",
+	"cache.count":         "%d cached entries (%s)",
+	"cache.pruned":        "Removed %d entries and %d unreferenced objects",
+	"cache.cleared":       "Cleared cache directory %s",
+
+	"update.offline":       "the update command needs to contact sources and cannot be used with --offline",
+	"update.scan_failed":   "failed to read the download path: %w",
+	"update.read_failed":   "failed to read the downloaded file: %w",
+	"update.found":         "Found %[1]d downloaded games in %[2]s",
+	"update.checking":      "Checking %s for updates",
+	"update.unchanged":     "Up to date",
+	"update.outdated":      "Upstream changed (%d depot manifests changed), check only",
+	"update.redownloading": "Upstream changed (%d depot manifests changed), downloading again",
+	"update.check_failed":  "Update check failed: %v",
+	"update.row_updated":   "%s: updated (%d depot manifests changed)",
+	"update.row_outdated":  "%s: outdated (%d depot manifests changed)",
+	"update.row_unchanged": "%s: up to date",
+	"update.row_failed":    "%s: failed (%s)",
+	"update.summary":       "Checked %d: %d changed, %d up to date, %d failed",
+	"update.failed":        "%d/%d update checks failed, first error: %w",
+
+	"library.read_failed":   "failed to read the library: %w",
+	"library.write_failed":  "failed to write the library: %w",
+	"library.record_failed": "Failed to record the download in the library: %v",
+	"library.bad_args":      "invalid list arguments: %v",
+	"library.bad_sort":      "invalid sort key: %s (expected appid, name, time or dlc)",
+	"library.counts":        "DLCs: %d, depot keys: %d",
+	"library.total":         "Showing %d of %d games",
+
+	"diff.usage":            "usage: diff <AppID> or diff <file1> <file2>",
+	"diff.no_previous":      "only one version of AppID %s is cached; a diff is available once upstream changes",
+	"diff.header":           "Comparing %s -> %s",
+	"diff.none":             "Both versions have the same entries",
+	"diff.depots_added":     "%d depots added: %s",
+	"diff.depots_removed":   "%d depots removed: %s",
+	"diff.manifest_changed": "Depot %s manifest: %s -> %s",
+	"diff.key_changed":      "%s depot key: %s -> %s",
+	"diff.dlcs_added":       "%d DLCs added: %s",
+	"diff.dlcs_removed":     "%d DLCs removed: %s",
+
+	"serve.bad_args":        "invalid serve arguments: %v",
+	"serve.bad_concurrency": "max-concurrent must be greater than 0: %d",
+	"serve.listen_failed":   "failed to listen: %w",
+	"serve.listening":       "HTTP server listening on %s (up to %d concurrent requests)",
+	"serve.stopping":        "Stopping HTTP server",
+	"serve.failed":          "HTTP server failed: %w",
+	"serve.busy":            "server is busy, try again later",
+	"serve.not_found":       "not found: %s",
+	"serve.bad_limit":       "invalid limit: %s",
+	"serve.request":         "%s %s %d %dms",
+	"serve.ui":              "Web UI: %s",
+
+	"web.title":              "ManifestHub manifest downloads",
+	"web.search_placeholder": "Game name",
+	"web.search":             "Search",
+	"web.searching":          "Searching...",
+	"web.no_results":         "No matching games found",
+	"web.error":              "Error: ",
+	"web.col_name":           "Name",
+	"web.col_appid":          "AppID",
+	"web.col_type":           "Type",
+	"web.col_key":            "Depot key",
+	"web.col_manifest":       "Manifest available",
+	"web.yes":                "yes",
+	"web.no":                 "no",
+	"web.dlc":                "DLC",
+	"web.dlc_loading":        "Loading DLCs...",
+	"web.dlc_none":           "This game has no DLCs",
+	"web.dlc_count":          "%d DLCs",
+	"web.has_depots":         "has depots",
+	"web.no_depots":          "no depots",
+	"web.download":           "Download .lua",
+	"web.downloading":        "Downloading and processing the manifest, please wait...",
+
+	"offline.skipped":         "Offline mode: skipped DepotKey patching and DLC lookup",
+	"offline.require_key":     "depot keys cannot be fetched in offline mode",
+	"offline.would_overwrite": "%s already exists; the cached manifest has no depot keys or DLCs in offline mode, so it was not overwritten (use --force to overwrite)",
+
+	"cmd.usage": `Usage:
+  ManifestHub-CLI                                   interactive mode
+  ManifestHub-CLI [--output text|json|ndjson] <AppID/link>...
+                                                    batch download
+  ManifestHub-CLI [--offline [--force]] <AppID>...  read manifests from the local cache only; --force overwrites downloaded files
+  ManifestHub-CLI index update                      download or refresh the local search index
+  ManifestHub-CLI update [--check]                  check downloaded games and download changed manifests again
+  ManifestHub-CLI list [--sort appid|name|time|dlc] [--desc] [--filter text] [--source text]
+                                                    list games in the download library
+  ManifestHub-CLI diff <AppID>                      compare the previous and current cached versions
+  ManifestHub-CLI diff <file1> <file2>              compare the entries of two Lua files
+  ManifestHub-CLI serve [--addr 127.0.0.1:8080] [--max-concurrent 4] [--ui]
+                                                    serve downloads, search, DLC and depot key lookups over HTTP; --ui adds a web page
+  ManifestHub-CLI cache ls                          list cached manifests
+  ManifestHub-CLI cache prune [30d]                 remove entries older than the given age and unreferenced objects
+  ManifestHub-CLI cache clear                       clear the cache
+  ManifestHub-CLI i18n check                        check that every catalog is complete`,
+}
@@ -0,0 +1,351 @@
+package main
+
+// 简体中文语言包
+var catalogZH = map[string]string{
+	"app.title":            "ManifestHub CLI - 新一代密钥获取工具",
+	"app.developer":        "开发者:LANREN",
+	"app.version":          "版本号:V1.2",
+	"app.input_closed":     "输入已关闭，程序退出",
+	"app.get_appid_failed": "获取AppID失败: %v",
+
+	"common.yes": "是",
+	"common.no":  "否",
+
+	"cmd.unknown_output":   "未知的输出格式: %s",
+	"cmd.unknown_index":    "未知的 index 子命令",
+	"cmd.unknown":          "未知命令: %s",
+	"cmd.output_failed":    "输出结果失败: %w",
+	"cmd.summary_failed":   "%s: 失败 (%s)\n",
+	"cmd.summary_ok":       "%s: 已保存到 %s, 添加 %d 个DLC, 耗时 %.2f秒\n",
+	"cmd.downloads_failed": "%d/%d 个下载失败, 首个错误: %w",
+	"cmd.failed":           "执行失败: %v",
+	"cmd.unknown_i18n":     "未知的 i18n 子命令",
+	"cmd.unknown_cache":    "未知的 cache 子命令",
+
+	"flag.output":      "输出格式: text / json / ndjson",
+	"flag.log_level":   "日志级别: quiet / normal / verbose / debug",
+	"flag.log_file":    "日志文件路径",
+	"flag.require_key": "没有 DepotKey 时视为下载失败",
+	"flag.extract_all": "来自 ZIP 源时把压缩包中的其余文件解压到 <下载路径>/<AppID>",
+	"flag.offline":     "只从本地缓存读取清单, 不访问下载源",
+	"flag.force":       "离线模式下允许用缓存中未修补的清单覆盖已下载的文件",
+
+	"config.create_failed":    "创建配置文件失败: %v, 使用默认路径",
+	"config.created":          "已创建默认配置文件: config.ini",
+	"config.read_failed":      "读取配置文件失败: %v, 使用默认路径",
+	"config.abs_failed":       "路径转换失败: %v, 使用原路径",
+	"config.bad_search_mode":  "未知的搜索模式: %s, 已忽略",
+	"config.bad_search_limit": "无效的 searchLimit: %s, 已忽略",
+	"config.bad_probe":        "无效的 probeResults: %s, 已忽略",
+	"config.ignored":          "%v, 已忽略",
+	"config.download_path":    "下载路径: %s",
+	"config.write_failed":     "写入配置文件失败: %w",
+	"config.bad_value":        "无效的 %s: %s, 已忽略",
+	"config.bad_size":         "无效的大小: %s",
+
+	"log.bad_level":    "未知的日志级别: %s (可选: quiet/normal/verbose/debug)",
+	"log.open_failed":  "打开日志文件失败: %w",
+	"log.setup_failed": "%v, 使用默认日志设置",
+	"log.prefix_error": "[错误] ",
+	"log.prefix_warn":  "[警告] ",
+	"log.prefix_debug": "[调试] ",
+
+	"download.start":   "开始下载: %s.lua",
+	"download.sources": "尝试以下下载源:",
+	"download.failed":  "下载失败: %v",
+	"download.elapsed": "耗时: %.2f秒",
+
+	"fetch.try_source":       "尝试源 #%d: %s",
+	"fetch.source_failed":    "源 #%d 失败: %w",
+	"fetch.source_ok":        "成功从源 #%d 下载",
+	"fetch.all_failed":       "所有 %d 个源尝试失败: %v",
+	"fetch.report":           "下载源尝试情况:",
+	"fetch.report_row":       " %s %s | 状态: %s | 耗时: %dms | %s",
+	"fetch.report_no_status": "-",
+
+	"http.new_request_failed": "创建请求失败: %w",
+	"http.request_failed":     "请求失败: %w",
+	"http.bad_status":         "HTTP状态码错误: %d",
+	"http.json_failed":        "解析JSON失败: %w",
+	"http.read_failed":        "读取响应失败: %w",
+	"http.too_large":          "响应超过大小上限 (%d 字节)",
+
+	"zip.trying":            "正在尝试 ZIP 源: %s",
+	"zip.retry":             "第 %d 次重试 Walftech 源...",
+	"zip.bad_status":        "状态码错误 %d(URL: %s)",
+	"zip.idle":              "检测到长时间无进展(%v)，已取消本次下载",
+	"zip.progress_pct":      "\r下载中: %.2f%% (%d/%d bytes)  %.2f KB/s",
+	"zip.progress":          "\r下载中: %d bytes  %.2f KB/s",
+	"zip.not_zip":           "解析ZIP失败: 返回内容不是有效的ZIP文件(URL: %s)",
+	"zip.parse_failed":      "解析ZIP失败: %w",
+	"zip.open_entry_failed": "打开ZIP内文件失败: %w",
+	"zip.read_entry_failed": "读取ZIP内文件失败: %w",
+	"zip.extracted":         "成功从 Walftech 源提取文件: %s(大小: %d字节)",
+	"zip.not_found":         "ZIP中未找到目标文件: %s(URL: %s)",
+	"zip.idle_timeout":      "长时间没有收到数据",
+	"zip.read_error":        "读取ZIP数据失败: %w",
+	"zip.will_retry":        "%v, 将重试...",
+	"zip.empty":             "ZIP源返回了空内容(URL: %s)",
+	"zip.resuming":          "从 %d 字节处继续下载",
+	"zip.size_mismatch":     "下载的文件大小 %d 与服务器报告的 %d 不一致",
+	"zip.stale_removed":     "已清理过期的未完成下载: %s",
+	"zip.archive_too_large": "ZIP 文件过大: %d 字节, 上限 %d 字节",
+	"zip.too_many_entries":  "ZIP 中文件过多: %d 个, 上限 %d 个",
+	"zip.unsafe_name":       "ZIP 中包含不安全的文件路径: %s",
+	"zip.entry_too_large":   "ZIP 中的文件 %s 过大: %d 字节, 上限 %d 字节",
+	"zip.ratio_too_high":    "ZIP 中的文件 %s 压缩比超过 %g, 可能是压缩炸弹",
+	"zip.total_too_large":   "ZIP 解压后总大小超过上限 %d 字节",
+	"zip.extracting":        "正在解压 ZIP 中的其余文件到: %s",
+	"zip.extracted_file":    " %s (%d字节)",
+	"zip.extract_done":      "已解压 %d 个文件到: %s",
+	"zip.extract_failed":    "解压 ZIP 失败: %v",
+	"zip.part_in_use":       "%s 正在被其他下载使用, 本次下载使用独立的临时文件, 不续传",
+
+	"depotkeys.try_source":          "尝试 DepotKey 源 #%d: %s",
+	"depotkeys.source_failed":       "DepotKey 源 #%d 失败: %w",
+	"depotkeys.source_status":       "DepotKey 源 #%d 状态码 %d",
+	"depotkeys.source_parse_failed": "DepotKey 源 #%d 解析失败: %w",
+	"depotkeys.ok":                  "成功从源 #%d 下载 depotkeys.json (%d个条目)",
+	"depotkeys.all_failed":          "所有 %d 个 DepotKey 源尝试失败: %v",
+	"depotkeys.download_failed":     "下载 DepotKeys 失败: %v",
+	"depotkeys.no_key":              "没有找到AppID %s 的 DepotKey",
+	"depotkeys.found":               "找到 AppID %s 的 DepotKey: %s",
+	"depotkeys.regex":               "使用的正则表达式: %s",
+	"depotkeys.match":               "发现匹配内容: %s",
+	"depotkeys.patch_target":        "发现需要修补的 addappid(%s)",
+	"depotkeys.replacement":         "替换为: %s",
+	"depotkeys.patched":             "已修补 DepotKey",
+	"depotkeys.no_target":           "未找到需要修补的 addappid(%s)",
+
+	"fs.mkdir_failed": "创建目录失败: %w",
+	"fs.save_failed":  "保存文件失败: %w",
+	"fs.open_failed":  "打开文件失败: %w",
+	"fs.saved":        "文件已保存到: %s (%d字节)",
+	"fs.read_failed":  "读取文件失败: %w",
+
+	"process.commented_line": "已注释: %s",
+	"process.commented":      "已注释 %d 行 setManifest",
+
+	"dlc.start":                "开始添加无仓库的DLC...",
+	"dlc.failed":               "添加DLC失败: %v",
+	"dlc.done":                 "DLC添加完成",
+	"dlc.main_failed":          "获取主游戏DLC失败: %w",
+	"dlc.provider":             "DLC信息来源: %s",
+	"dlc.detail_failed":        "获取DLC %s 信息失败: %v",
+	"dlc.keep":                 "保留",
+	"dlc.exclude":              "排除",
+	"dlc.decision":             " [%s] %s %s (%s, 来源: %s)",
+	"dlc.filter_summary":       "DLC 过滤结果 (保留 %d / 共 %d):",
+	"dlc.none":                 "未找到无仓库的DLC",
+	"dlc.all_exist":            "所有无仓库的DLC已存在于解锁文件中",
+	"dlc.write_failed":         "写入DLC %s 失败: %v",
+	"dlc.added":                "添加DLC: %s",
+	"dlc.depots_unknown":       "%s 未提供DLC %s 的仓库信息, 无法判断是否为无仓库的DLC, 跳过 (配置 dlcUnknownDepots = add 可添加)",
+	"dlc.depots_unknown_added": "%s 未提供DLC %s 的仓库信息, 按 dlcUnknownDepots = add 视为无仓库的DLC",
+
+	"filter.bad_regex":          "DLC名称正则表达式无效: %w",
+	"filter.no_rules":           "无过滤规则",
+	"filter.denied":             "位于黑名单",
+	"filter.allowed":            "位于白名单",
+	"filter.not_allowed":        "不在白名单",
+	"filter.type_excluded":      "类型 %s 被排除",
+	"filter.name_excluded":      "名称匹配 %s",
+	"filter.passed":             "通过过滤",
+	"filter.bad_unknown_depots": "dlcUnknownDepots 只能为 add 或 skip: %s",
+
+	"provider.none":        "未配置应用信息源",
+	"provider.unknown":     "未知的应用信息源: %s",
+	"provider.not_found":   "未找到AppID %s 的信息",
+	"provider.dlc_string":  "DLC 字段是字符串: %s",
+	"provider.dlc_type":    "DLC 字段的类型异常: %T",
+	"provider.depots_type": "depots 字段不是 map: %T",
+
+	"index.downloading":       "正在下载应用列表: %s",
+	"index.download_failed":   "下载应用列表失败: %w",
+	"index.bad_status":        "应用列表API返回错误状态码: %d",
+	"index.parse_failed":      "解析应用列表失败: %w",
+	"index.empty":             "应用列表为空",
+	"index.marshal_failed":    "序列化索引失败: %w",
+	"index.save_failed":       "保存索引失败: %w",
+	"index.updated":           "本地索引已更新: %s (%d个条目)",
+	"index.missing":           "本地索引不存在: %s, 请先运行 \"index update\"",
+	"index.read_failed":       "读取本地索引失败: %w",
+	"index.load_parse_failed": "解析本地索引失败: %w",
+	"index.searching":         "正在搜索本地索引: %s (更新于 %s)",
+
+	"search.type_dlc":       "DLC",
+	"search.type_game":      "游戏",
+	"search.type_tool":      "工具",
+	"search.type_music":     "音乐",
+	"search.type_video":     "视频",
+	"search.type_demo":      "试玩",
+	"search.probing":        "正在检查各结果的清单是否可用...",
+	"search.known_failed":   "获取收录信息失败: %v",
+	"search.row":            " %d. [%s] %-30s | AppID: %-8d | 类型: %-4s | 已收录: %s\n",
+	"search.empty_name":     "游戏名称不能为空",
+	"search.online_failed":  "在线搜索失败: %v, 改用本地索引",
+	"search.no_match":       "未找到与 '%s' 匹配的游戏",
+	"search.found":          "找到 %d 个匹配的游戏:",
+	"search.searching":      "正在搜索游戏: %s",
+	"search.request_url":    "请求URL: %s",
+	"search.request_failed": "搜索请求失败: %w",
+	"search.bad_status":     "搜索API返回错误状态码: %d",
+	"search.parse_failed":   "解析搜索结果失败: %w（可能API返回格式变更）",
+	"search.failed":         "搜索游戏失败: %w",
+	"search.none":           "未找到任何匹配的游戏，请重新输入",
+
+	"input.read_failed":        "读取输入失败: %w",
+	"input.empty":              "输入不能为空",
+	"input.bad_appid":          "提取的AppID不是有效数字: %s",
+	"input.extracted":          "从输入中提取到AppID: %d",
+	"input.numeric":            "输入为纯数字AppID: %d",
+	"input.invalid":            "无效格式，请输入含空格的游戏名称、Steam URL或纯数字AppID",
+	"input.prompt":             "请输入游戏名称/AppID/Steam链接/SteamDB链接:",
+	"input.fallback_search":    "无法直接提取AppID，将尝试按名称 '%s' 搜索...",
+	"input.select":             "请输入需要下载的游戏序号:",
+	"input.select_more":        "请输入需要下载的游戏序号(输入 m 显示更多, 剩余 %d 个):",
+	"input.select_read_failed": "读取选择失败: %w",
+	"input.select_nan":         "序号必须是数字，请输入 1-%d 之间的序号",
+	"input.select_range":       "无效序号，请选择 1-%d 之间的数字",
+	"input.select_unavailable": "%s (AppID: %d) 在所有下载源中都没有清单, 下载可能失败",
+	"input.selected":           "已选择游戏: %s (AppID: %d)",
+	"input.select_unknown":     "无法确认 %s (AppID: %d) 是否有清单, 仍尝试下载",
+
+	"i18n.bad_language":  "未知的语言: %s",
+	"i18n.missing":       "语言包 %s 缺少: %s",
+	"i18n.missing_count": "%d 个翻译缺失",
+	"i18n.ok":            "所有语言包完整 (%d 个键)",
+
+	"error.manifest_not_found": "未找到清单",
+	"error.all_sources_failed": "所有下载源均失败",
+	"error.network":            "网络错误",
+	"error.invalid_zip":        "无效的ZIP文件",
+	"error.no_depot_key":       "没有可用的 DepotKey",
+	"error.invalid_input":      "无效输入",
+
+	"cancel.canceled":          "操作已取消: %w",
+	"cancel.download_canceled": "已取消 AppID %s 的下载",
+	"cancel.terminated":        "收到终止信号, 程序退出",
+	"cancel.search_canceled":   "已取消本次搜索",
+
+	"local.start":       "开始处理本地文件: %s",
+	"local.appid":       "使用 AppID %s (来自 %s)",
+	"local.reading":     "读取本地文件: %s",
+	"local.read_failed": "读取本地文件失败: %w",
+	"local.too_large":   "本地文件过大: %d 字节, 上限 %d 字节",
+	"local.no_appid":    "无法从文件名确定 AppID: %s, 请将文件命名为 <AppID>.zip 或 <AppID>.lua",
+
+	"git.not_repo":      "%s 不是可用的 git 仓库: %w",
+	"git.not_installed": "未找到 git 命令: %w",
+	"git.no_file":       "%s 中没有 %s.lua",
+	"git.read":          "从本地仓库的 %s 读取",
+	"git.not_found":     "本地仓库 %[2]s 中没有 AppID %[1]s 的分支",
+
+	"source.unknown":       "无法识别的下载源 %q (支持 http(s) 地址模板及 git:、dir:、zip: 前缀)",
+	"source.dir_not_found": "目录 %[2]s 中没有 AppID %[1]s 的清单",
+
+	"cache.read_failed":   "读取缓存失败: %w",
+	"cache.write_failed":  "写入缓存失败: %w",
+	"cache.remove_failed": "删除缓存失败: %w",
+	"cache.bad_object":    "缓存内容 %s 已损坏",
+	"cache.disabled":      "清单缓存未启用(配置 cache = false)",
+	"cache.miss":          "缓存中没有 AppID %s 的清单",
+	"cache.hit":           "使用缓存的清单 (下载于 %s, 来自 %s)",
+	"cache.not_modified":  "清单未变化, 使用缓存 (%s)",
+	"cache.ignored":       "已忽略缓存错误: %v",
+	"cache.bad_age":       "无效的时长: %s (例如 30d、12h)",
+	"cache.count":         "共 %d 条缓存记录 (%s)",
+	"cache.pruned":        "已删除 %d 条记录和 %d 个无引用的内容",
+	"cache.cleared":       "已清空缓存目录 %s",
+
+	"update.offline":       "update 命令需要访问下载源, 不能与 --offline 同时使用",
+	"update.scan_failed":   "读取下载路径失败: %w",
+	"update.read_failed":   "读取已下载的文件失败: %w",
+	"update.found":         "在 %[2]s 中找到 %[1]d 个已下载的游戏",
+	"update.checking":      "检查 %s 是否有更新",
+	"update.unchanged":     "与上游相同, 无需更新",
+	"update.outdated":      "上游有变化 (%d 个 Depot 的清单变化), 仅检查不下载",
+	"update.redownloading": "上游有变化 (%d 个 Depot 的清单变化), 重新下载",
+	"update.check_failed":  "检查更新失败: %v",
+	"update.row_updated":   "%s: 已更新 (%d 个 Depot 的清单变化)",
+	"update.row_outdated":  "%s: 有更新 (%d 个 Depot 的清单变化)",
+	"update.row_unchanged": "%s: 无变化",
+	"update.row_failed":    "%s: 失败 (%s)",
+	"update.summary":       "共检查 %d 个, 有变化 %d 个, 无变化 %d 个, 失败 %d 个",
+	"update.failed":        "%d/%d 个游戏检查更新失败, 首个错误: %w",
+
+	"library.read_failed":   "读取下载库失败: %w",
+	"library.write_failed":  "写入下载库失败: %w",
+	"library.record_failed": "记录到下载库失败: %v",
+	"library.bad_args":      "无效的 list 参数: %v",
+	"library.bad_sort":      "无效的排序方式: %s (可选 appid、name、time、dlc)",
+	"library.counts":        "DLC: %d, DepotKey: %d",
+	"library.total":         "显示 %d 个, 共 %d 个游戏",
+
+	"diff.usage":            "用法: diff <AppID> 或 diff <文件1> <文件2>",
+	"diff.no_previous":      "缓存中只有 AppID %s 的一个版本, 上游内容变化后才能比较",
+	"diff.header":           "比较 %s -> %s",
+	"diff.none":             "两个版本的条目相同",
+	"diff.depots_added":     "新增 %d 个 Depot: %s",
+	"diff.depots_removed":   "删除 %d 个 Depot: %s",
+	"diff.manifest_changed": "Depot %s 的 ManifestID: %s -> %s",
+	"diff.key_changed":      "%s 的 DepotKey: %s -> %s",
+	"diff.dlcs_added":       "新增 %d 个DLC: %s",
+	"diff.dlcs_removed":     "删除 %d 个DLC: %s",
+
+	"serve.bad_args":        "无效的 serve 参数: %v",
+	"serve.bad_concurrency": "并发数必须大于 0: %d",
+	"serve.listen_failed":   "监听地址失败: %w",
+	"serve.listening":       "HTTP 服务已启动: %s (最多同时处理 %d 个请求)",
+	"serve.stopping":        "正在停止 HTTP 服务",
+	"serve.failed":          "HTTP 服务异常退出: %w",
+	"serve.busy":            "服务器繁忙, 请稍后重试",
+	"serve.not_found":       "未找到: %s",
+	"serve.bad_limit":       "无效的 limit: %s",
+	"serve.request":         "%s %s %d %dms",
+	"serve.ui":              "网页界面: %s",
+
+	"web.title":              "ManifestHub 清单下载",
+	"web.search_placeholder": "输入游戏名称",
+	"web.search":             "搜索",
+	"web.searching":          "正在搜索...",
+	"web.no_results":         "没有找到匹配的游戏",
+	"web.error":              "出错了: ",
+	"web.col_name":           "名称",
+	"web.col_appid":          "AppID",
+	"web.col_type":           "类型",
+	"web.col_key":            "DepotKey",
+	"web.col_manifest":       "清单可用",
+	"web.yes":                "有",
+	"web.no":                 "无",
+	"web.dlc":                "DLC",
+	"web.dlc_loading":        "正在获取DLC...",
+	"web.dlc_none":           "该游戏没有DLC",
+	"web.dlc_count":          "共 %d 个DLC",
+	"web.has_depots":         "有 Depot",
+	"web.no_depots":          "无 Depot",
+	"web.download":           "下载 .lua",
+	"web.downloading":        "正在下载并处理清单, 请稍候...",
+
+	"offline.skipped":         "离线模式: 已跳过 DepotKey 修补与DLC添加",
+	"offline.require_key":     "离线模式下无法获取 DepotKey",
+	"offline.would_overwrite": "%s 已存在, 离线模式下缓存的清单没有 DepotKey 与DLC, 不覆盖该文件 (使用 --force 强制覆盖)",
+
+	"cmd.usage": `用法:
+  ManifestHub-CLI                                   进入交互模式
+  ManifestHub-CLI [--output text|json|ndjson] <AppID/链接>...
+                                                    批量下载
+  ManifestHub-CLI [--offline [--force]] <AppID>...  只从本地缓存读取清单, --force 时覆盖已下载的文件
+  ManifestHub-CLI index update                      下载或刷新本地搜索索引
+  ManifestHub-CLI update [--check]                  检查下载路径中的游戏, 重新下载上游有变化的清单
+  ManifestHub-CLI list [--sort appid|name|time|dlc] [--desc] [--filter 关键词] [--source 下载源]
+                                                    列出下载库中的游戏
+  ManifestHub-CLI diff <AppID>                      比较缓存中上一个版本与当前版本的条目
+  ManifestHub-CLI diff <文件1> <文件2>              比较两个 Lua 文件的条目
+  ManifestHub-CLI serve [--addr 127.0.0.1:8080] [--max-concurrent 4] [--ui]
+                                                    以 HTTP 接口提供下载、搜索、DLC 及 DepotKey 查询, --ui 同时提供网页界面
+  ManifestHub-CLI cache ls                          列出缓存的清单
+  ManifestHub-CLI cache prune [30d]                 删除超过指定时长的记录及无引用的内容
+  ManifestHub-CLI cache clear                       清空缓存
+  ManifestHub-CLI i18n check                        检查各语言包是否完整`,
+}
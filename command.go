@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// 执行命令行命令
+func RunCommand(ctx context.Context, config *Config, args []string, out io.Writer) error {
+	switch config.Output {
+	case OutputText, OutputJSON, OutputNDJSON:
+	default:
+		return usageError(Err("cmd.unknown_output", config.Output))
+	}
+
+	switch args[0] {
+	case "index":
+		if len(args) < 2 || args[1] != "update" {
+			return usageError(Err("cmd.unknown_index"))
+		}
+		return UpdateIndex(ctx, config)
+	case "serve":
+		return RunServe(ctx, config, args[1:])
+	case "diff":
+		return RunDiff(config, args[1:], out)
+	case "list":
+		return RunList(config, args[1:], out)
+	case "update":
+		return RunUpdate(ctx, config, args[1:], out)
+	case "cache":
+		return RunCacheCommand(config, args[1:], out)
+	case "i18n":
+		if len(args) < 2 || args[1] != "check" {
+			return usageError(Err("cmd.unknown_i18n"))
+		}
+		return RunI18nCheck()
+	case "help", "-h", "--help":
+		fmt.Fprintln(console, T("cmd.usage"))
+		return nil
+	}
+
+	// 其余参数视为待下载的 AppID、链接或本地 ZIP/Lua 文件
+	if _, ok := LocalPath(args[0]); !ok {
+		if _, err := ExtractAppID(args[0]); err != nil {
+			return usageError(Err("cmd.unknown", args[0]))
+		}
+	}
+	return RunDownloads(ctx, config, args, out)
+}
+
+// 输入或参数无效的错误, 消息后附加用法说明
+func usageError(err error) error {
+	return markError(ErrInvalidInput, fmt.Errorf("%w\n%s", err, T("cmd.usage")))
+}
+
+// 批量下载并按输出格式输出结果
+func RunDownloads(ctx context.Context, config *Config, inputs []string, out io.Writer) error {
+	results := []*DownloadResult{}
+	var firstErr error
+	failed := 0
+
+	for _, input := range inputs {
+		// 收到中断信号后不再开始新的下载
+		if ctx.Err() != nil {
+			break
+		}
+		printDivision()
+
+		// 本地文件直接处理, 否则解析 AppID 后下载
+		var result *DownloadResult
+		var err error
+		if path, ok := LocalPath(input); ok {
+			result, err = DownloadFile(ctx, path, config)
+		} else {
+			var appID int
+			appID, err = ExtractAppID(input)
+			if err != nil {
+				result = &DownloadResult{AppID: input, DLCsAdded: []string{}, Error: err.Error()}
+			} else {
+				result, err = Download(ctx, strconv.Itoa(appID), config)
+			}
+		}
+		if err != nil {
+			failed++
+			result.ExitCode = ExitCode(err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			logger.Error(T("download.failed", err), "appid", result.AppID)
+		}
+
+		// NDJSON 每完成一个就输出一行
+		if config.Output == OutputNDJSON {
+			if err := writeRecord(out, config.Output, result); err != nil {
+				return err
+			}
+		}
+		results = append(results, result)
+	}
+
+	switch config.Output {
+	case OutputJSON:
+		if err := writeRecords(out, config.Output, results); err != nil {
+			return err
+		}
+	case OutputText:
+		fmt.Fprintln(out, Division)
+		for _, result := range results {
+			if result.Error != "" {
+				fmt.Fprintf(out, T("cmd.summary_failed"), result.AppID, result.Error)
+				continue
+			}
+			fmt.Fprintf(out, T("cmd.summary_ok"),
+				result.AppID, result.OutputPath, len(result.DLCsAdded), float64(result.Timings["total"])/1000)
+		}
+	}
+
+	if failed > 0 {
+		return Err("cmd.downloads_failed", failed, len(results), firstErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return Err("cancel.canceled", err)
+	}
+	return nil
+}
+
+// 按输出格式写出单个记录: json 缩进输出, ndjson 输出为一行
+func writeRecord(out io.Writer, output string, record any) error {
+	encoder := json.NewEncoder(out)
+	if output == OutputJSON {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(record); err != nil {
+		return Err("cmd.output_failed", err)
+	}
+	return nil
+}
+
+// 按输出格式写出记录列表: json 输出一个数组(没有记录时为空数组), ndjson 每行一个记录
+func writeRecords[T any](out io.Writer, output string, records []T) error {
+	if output == OutputNDJSON {
+		for _, record := range records {
+			if err := writeRecord(out, output, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if records == nil {
+		records = []T{}
+	}
+	return writeRecord(out, output, records)
+}
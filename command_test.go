@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteRecords(t *testing.T) {
+	type record struct {
+		ID string `json:"id"`
+	}
+	tests := []struct {
+		name    string
+		output  string
+		records []record
+		want    string
+	}{
+		{"json array", OutputJSON, []record{{"1"}, {"2"}}, "[\n  {\n    \"id\": \"1\"\n  },\n  {\n    \"id\": \"2\"\n  }\n]\n"},
+		{"json empty array", OutputJSON, nil, "[]\n"},
+		{"ndjson one record per line", OutputNDJSON, []record{{"1"}, {"2"}}, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n"},
+		{"ndjson no records", OutputNDJSON, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeRecords(&out, tt.output, tt.records); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("writeRecords = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+// 检查 NDJSON 输出: 每行都是一个 JSON 对象, 返回行数
+func ndjsonLines(t *testing.T, out string) int {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if out == "" {
+		return 0
+	}
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", line, err)
+		}
+	}
+	return len(lines)
+}
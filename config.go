@@ -1,9 +1,10 @@
 package main
 
 import (
-	"fmt"
+	_ "embed"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -11,6 +12,14 @@ import (
 func LoadConfig() *Config {
 	config := &Config{
 		DownloadPath: ".", // 默认当前目录
+		DLCFilter:    NewDLCFilter(),
+		SearchMode:   SearchModeFallback,
+		IndexPath:    "appindex.json",
+		SearchLimit:  10,
+		ProbeResults: true,
+		LogLevel:     "normal",
+		CacheEnabled: true,
+		CacheDir:     "manifestcache",
 	}
 
 	// 检查配置文件是否存在
@@ -18,20 +27,21 @@ func LoadConfig() *Config {
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		// 配置文件不存在, 创建默认配置
 		if err := CreateConfig(); err != nil {
-			fmt.Printf("创建配置文件失败: %v, 使用默认路径\n", err)
+			logger.Warn(T("config.create_failed", err))
 			return config
 		}
-		fmt.Println("已创建默认配置文件: config.ini")
+		logger.Info(T("config.created"))
 	}
 
 	// 尝试读取配置文件
 	data, err := os.ReadFile(configFile)
 	if err != nil {
-		fmt.Printf("读取配置文件失败: %v, 使用默认路径\n", err)
+		logger.Warn(T("config.read_failed", err))
 		return config
 	}
 
-	// 解析配置文件
+	// 解析配置文件, source 可出现多次, 按顺序组成下载源列表
+	var sources []Source
 	lines := strings.Split(string(data), "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -40,41 +50,185 @@ func LoadConfig() *Config {
 			continue
 		}
 
-		if strings.HasPrefix(line, "downloadPath") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				path := strings.TrimSpace(parts[1])
-				// 移除可能存在的引号
-				path = strings.Trim(path, `"'`)
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		// 移除可能存在的引号
+		value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
 
-				// 检查路径是否有效
-				if path != "" {
-					// 转换为绝对路径
-					absPath, err := filepath.Abs(path)
-					if err != nil {
-						fmt.Printf("路径转换失败: %v, 使用原路径\n", err)
-						config.DownloadPath = path
-					} else {
-						config.DownloadPath = absPath
-					}
+		switch {
+		case key == "source":
+			if value == "" {
+				continue
+			}
+			source, err := NewSource(value)
+			if err != nil {
+				logger.Warn(T("config.ignored", err))
+				continue
+			}
+			sources = append(sources, source)
+		case key == "downloadPath":
+			// 检查路径是否有效
+			if value != "" {
+				// 转换为绝对路径
+				absPath, err := filepath.Abs(value)
+				if err != nil {
+					logger.Warn(T("config.abs_failed", err))
+					config.DownloadPath = value
+				} else {
+					config.DownloadPath = absPath
+				}
+			}
+		case key == "searchMode":
+			switch value {
+			case SearchModeOnline, SearchModeLocal, SearchModeFallback:
+				config.SearchMode = value
+			default:
+				logger.Warn(T("config.bad_search_mode", value))
+			}
+		case key == "indexPath":
+			if value != "" {
+				config.IndexPath = value
+			}
+		case key == "searchLimit":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				config.SearchLimit = n
+			} else {
+				logger.Warn(T("config.bad_search_limit", value))
+			}
+		case key == "probeResults":
+			if b, err := strconv.ParseBool(value); err == nil {
+				config.ProbeResults = b
+			} else {
+				logger.Warn(T("config.bad_probe", value))
+			}
+		case key == "cache":
+			if b, err := strconv.ParseBool(value); err == nil {
+				config.CacheEnabled = b
+			} else {
+				logger.Warn(T("config.bad_value", key, value))
+			}
+		case key == "cacheDir":
+			if value != "" {
+				config.CacheDir = value
+			}
+		case key == "language":
+			config.Language = value
+		case key == "logLevel":
+			config.LogLevel = value
+		case key == "logFile":
+			config.LogFile = value
+		case key == "appInfoProviders":
+			var providers []AppInfoProvider
+			for _, name := range splitList(value) {
+				provider, err := NewAppInfoProvider(name)
+				if err != nil {
+					logger.Warn(T("config.ignored", err))
+					continue
 				}
-				break
+				providers = append(providers, provider)
+			}
+			if len(providers) > 0 {
+				AppInfoProviders = providers
+			}
+		case key == "zipMaxArchiveSize", key == "zipMaxEntrySize", key == "zipMaxTotalSize":
+			size, err := parseSize(value)
+			if err != nil {
+				logger.Warn(T("config.bad_value", key, value))
+				continue
+			}
+			switch key {
+			case "zipMaxArchiveSize":
+				ZipLimits.MaxArchiveSize = size
+			case "zipMaxEntrySize":
+				ZipLimits.MaxEntrySize = size
+			default:
+				ZipLimits.MaxTotalSize = size
+			}
+		case key == "zipExtractAll":
+			if b, err := strconv.ParseBool(value); err == nil {
+				config.ExtractAll = b
+			} else {
+				logger.Warn(T("config.bad_value", key, value))
 			}
+		case key == "zipMaxEntries":
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				ZipLimits.MaxEntries = n
+			} else {
+				logger.Warn(T("config.bad_value", key, value))
+			}
+		case key == "zipMaxRatio":
+			if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 {
+				ZipLimits.MaxRatio = f
+			} else {
+				logger.Warn(T("config.bad_value", key, value))
+			}
+		case key == "dlcExcludeTypes":
+			config.DLCFilter.SetExcludeTypes(value)
+		case key == "dlcExcludeName":
+			if err := config.DLCFilter.SetExcludeName(value); err != nil {
+				logger.Warn(T("config.ignored", err))
+			}
+		case key == "dlcUnknownDepots":
+			if err := config.DLCFilter.SetUnknownDepots(value); err != nil {
+				logger.Warn(T("config.ignored", err))
+			}
+		case strings.HasPrefix(key, "dlcAllow."):
+			config.DLCFilter.SetList(config.DLCFilter.Allow, strings.TrimPrefix(key, "dlcAllow."), value)
+		case strings.HasPrefix(key, "dlcDeny."):
+			config.DLCFilter.SetList(config.DLCFilter.Deny, strings.TrimPrefix(key, "dlcDeny."), value)
 		}
 	}
 
-	fmt.Printf("下载路径: %s\n", config.DownloadPath)
+	// 配置了下载源时替换默认列表
+	if len(sources) > 0 {
+		Sources = sources
+	}
+
+	logger.Info(T("config.download_path", config.DownloadPath))
 	return config
 }
 
+// 默认配置文件的内容, 与仓库中的 config.example.ini 相同
+//
+//go:embed config.example.ini
+var defaultConfig []byte
+
 // 创建默认配置文件
 func CreateConfig() error {
-	// 默认配置内容
-	content := `downloadPath = "."`
-
-	// 写入配置文件
-	if err := os.WriteFile("config.ini", []byte(content), 0644); err != nil {
-		return fmt.Errorf("写入配置文件失败: %v", err)
+	if err := os.WriteFile("config.ini", defaultConfig, 0644); err != nil {
+		return Err("config.write_failed", err)
 	}
 	return nil
 }
+
+// 解析大小, 支持 B/KB/MB/GB 单位(按 1024 进位), 无单位时按字节计
+func parseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, Err("config.bad_size", value)
+	}
+	return n * multiplier, nil
+}
+
+// 返回第一个非空值
+func orDefault(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -9,6 +11,100 @@ import (
 // 配置结构体
 type Config struct {
 	DownloadPath string
+	DLCFilter    *DLCFilter
+	SearchMode   string // 搜索模式: online / local / fallback
+	IndexPath    string // 本地搜索索引路径
+	SearchLimit  int    // 每页显示的搜索结果数
+	ProbeResults bool   // 搜索时检查各结果的清单是否可用
+	Output       string // 输出格式: text / json / ndjson
+	LogLevel     string // 日志级别: quiet / normal / verbose / debug
+	LogFile      string // 日志文件路径, 为空时不写入文件
+	Language     string // 界面语言: zh-CN / en
+	RequireKey   bool   // 没有 DepotKey 时视为下载失败
+	ExtractAll   bool   // 来自 ZIP 源时把压缩包中的其余文件解压到 <下载路径>/<AppID>
+	CacheEnabled bool   // 是否缓存下载的清单
+	CacheDir     string // 清单缓存目录
+	Offline      bool   // 只从缓存读取清单, 不访问下载源
+	Force        bool   // 离线模式下允许覆盖已下载的文件
+}
+
+// 输出格式
+const (
+	OutputText   = "text"   // 文字输出
+	OutputJSON   = "json"   // JSON 输出
+	OutputNDJSON = "ndjson" // 每行一个 JSON 记录
+)
+
+// 多源下载的结果
+type FetchResult struct {
+	Data        []byte          // 下载的数据
+	Source      string          // 成功的源地址
+	Archive     string          // 来自 ZIP 时压缩包的路径
+	TempArchive bool            // 压缩包为下载的临时文件, 使用后需删除
+	Attempts    []SourceAttempt // 各源的尝试记录
+}
+
+// 单次下载的结果
+type DownloadResult struct {
+	AppID            string           `json:"appid"`
+	Name             string           `json:"name,omitempty"`            // 游戏名称, 获取失败时为空
+	Source           string           `json:"source,omitempty"`          // 成功的下载源
+	Attempts         []SourceAttempt  `json:"attempts,omitempty"`        // 各下载源的尝试记录
+	Bytes            int              `json:"bytes"`                     // 保存的文件大小
+	CommentedCount   int              `json:"commented_setmanifest"`     // 注释的 setManifest 行数
+	DepotKeysPatched int              `json:"depot_keys_patched"`        // 修补的 DepotKey 数量
+	DLCsAdded        []string         `json:"dlcs_added"`                // 添加的DLC AppID
+	DLCProvider      string           `json:"dlc_provider,omitempty"`    // 提供DLC列表的应用信息源
+	OutputPath       string           `json:"output_path,omitempty"`     // 保存路径
+	ExtractDir       string           `json:"extract_dir,omitempty"`     // 完整解压 ZIP 的目录
+	ExtractedFiles   []string         `json:"extracted_files,omitempty"` // 解压的文件(相对于解压目录)
+	Timings          map[string]int64 `json:"timings_ms"`                // 各步骤耗时(毫秒)
+	Warnings         []string         `json:"warnings,omitempty"`        // 不影响结果的错误
+	Error            string           `json:"error,omitempty"`           // 导致下载失败的错误
+	ExitCode         int              `json:"exit_code,omitempty"`       // 失败时对应的退出码
+}
+
+// 搜索模式
+const (
+	SearchModeOnline   = "online"   // 仅在线搜索
+	SearchModeLocal    = "local"    // 仅本地索引
+	SearchModeFallback = "fallback" // 在线搜索失败时使用本地索引
+)
+
+// Steam 应用列表API(用于构建本地索引)
+const AppListURL = "https://api.steampowered.com/ISteamApps/GetAppList/v2/"
+
+// Steam 应用列表响应结构
+type AppListResponse struct {
+	AppList struct {
+		Apps []Game `json:"apps"`
+	} `json:"applist"`
+}
+
+// 本地搜索索引
+type AppIndex struct {
+	Updated time.Time `json:"updated"`
+	Apps    []Game    `json:"apps"`
+}
+
+// DLC过滤规则
+type DLCFilter struct {
+	ExcludeTypes map[string]bool            // 排除的DLC类型(小写, 如 music、video)
+	ExcludeName  *regexp.Regexp             // 按名称排除的正则表达式
+	Allow        map[string]map[string]bool // 每个游戏的DLC白名单(游戏AppID -> DLC AppID)
+	Deny         map[string]map[string]bool // 每个游戏的DLC黑名单(游戏AppID -> DLC AppID)
+	AddUnknown   bool                       // 是否添加信息源未提供仓库信息的DLC(如来自商店接口)
+}
+
+// 应用详情(游戏或DLC)
+type AppDetail struct {
+	AppID       string
+	Name        string
+	Type        string
+	DLCs        []string
+	HasDepots   bool
+	DepotsKnown bool   // 信息源是否提供仓库信息, 为 false 时 HasDepots 无意义
+	Provider    string // 提供信息的来源
 }
 
 // 输出分割
@@ -17,6 +113,25 @@ var Division = strings.Repeat("=", 40)
 // DLC信息API
 const DLCInfoURL = "https://api.steamcmd.net/v1/info/%s"
 
+// Steam 商店应用详情API
+const StoreDetailsURL = "https://store.steampowered.com/api/appdetails?appids=%s"
+
+// 应用信息源(按顺序尝试)
+var AppInfoProviders = []AppInfoProvider{
+	steamCMDProvider{},
+	steamStoreProvider{},
+}
+
+// Steam 商店应用详情结构
+type StoreDetails map[string]struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		DLC  []int  `json:"dlc"`
+	} `json:"data"`
+}
+
 // DLC信息结构
 type DLCInfo struct {
 	Data map[string]struct {
@@ -36,18 +151,30 @@ type LoadGamesResponse struct {
 type Game struct {
 	AppID int    `json:"appid"`
 	Name  string `json:"name"`
-}
+	Type  string `json:"type,omitempty"` // 类型(game/dlc/application等), 可能为空
 
-// 下载源
-var Sources = []string{
-	"https://raw.githubusercontent.com/SteamAutoCracks/ManifestHub/%s/%s.lua", // 原始源
-	"https://cdn.jsdelivr.net/gh/SteamAutoCracks/ManifestHub@%s/%s.lua",       // jsDelivr CDN
-	"https://gcore.jsdelivr.net/gh/SteamAutoCracks/ManifestHub@%s/%s.lua",     // G-Core CDN
-	"https://fastly.jsdelivr.net/gh/SteamAutoCracks/ManifestHub@%s/%s.lua",    // Fastly CDN
+	Availability Availability `json:"-"` // 下载源中是否存在清单
 }
 
-// 新增zip源
-const zipSource = "https://walftech.com/proxy.php?url=https://steamgames554.s3.us-east-1.amazonaws.com/%s.zip"
+// 清单可用性的检查结果
+type Availability int
+
+const (
+	AvailabilityUnprobed  Availability = iota // 未检查
+	AvailabilityUnknown                       // 检查失败(超时、网络错误、不支持 HEAD 等), 无法确定
+	AvailabilityMissing                       // 所有下载源都确认没有清单(404 或本地源中不存在)
+	AvailabilityAvailable                     // 至少一个下载源有清单
+)
+
+// 下载源, 按顺序尝试, 可在配置文件中替换
+var Sources = []Source{
+	urlSource{"https://raw.githubusercontent.com/SteamAutoCracks/ManifestHub/%s/%s.lua"}, // 原始源
+	urlSource{"https://cdn.jsdelivr.net/gh/SteamAutoCracks/ManifestHub@%s/%s.lua"},       // jsDelivr CDN
+	urlSource{"https://gcore.jsdelivr.net/gh/SteamAutoCracks/ManifestHub@%s/%s.lua"},     // G-Core CDN
+	urlSource{"https://fastly.jsdelivr.net/gh/SteamAutoCracks/ManifestHub@%s/%s.lua"},    // Fastly CDN
+	// Walftech ZIP 源
+	zipArchiveSource{"https://walftech.com/proxy.php?url=https://steamgames554.s3.us-east-1.amazonaws.com/%s.zip"},
+}
 
 // DepotKeys 镜像源
 var DepotkeySources = []string{
@@ -62,3 +189,13 @@ var DepotkeySources = []string{
 var httpClient = &http.Client{
 	Timeout: 5 * time.Second, // 5秒超时
 }
+
+// 按数值比较 AppID, 不是数字时按字符串比较
+func appIDLess(a, b string) bool {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+	if errX != nil || errY != nil {
+		return a < b
+	}
+	return x < y
+}
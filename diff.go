@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 执行 diff 命令: diff <AppID> 比较缓存中上一个版本与当前版本, diff <文件1> <文件2> 比较两个 Lua 文件
+func RunDiff(config *Config, args []string, out io.Writer) error {
+	var before, after *diffVersion
+	var err error
+	switch len(args) {
+	case 1:
+		appID, err := ExtractAppID(args[0])
+		if err != nil {
+			return markError(ErrInvalidInput, err)
+		}
+		if before, after, err = cachedVersions(OpenCache(config), strconv.Itoa(appID)); err != nil {
+			return err
+		}
+	case 2:
+		if before, err = readDiffFile(args[0]); err != nil {
+			return err
+		}
+		if after, err = readDiffFile(args[1]); err != nil {
+			return err
+		}
+	default:
+		return usageError(Err("diff.usage"))
+	}
+
+	diff := DiffManifests(ParseManifest(before.Data), ParseManifest(after.Data))
+	if config.Output != OutputText {
+		return writeRecord(out, config.Output, diff)
+	}
+
+	fmt.Fprintln(out, Division)
+	fmt.Fprintln(out, T("diff.header", before.Name, after.Name))
+	if diff.Empty() {
+		fmt.Fprintln(out, T("diff.none"))
+		return nil
+	}
+	printIDs := func(key string, ids []string) {
+		if len(ids) > 0 {
+			fmt.Fprintln(out, T(key, len(ids), strings.Join(ids, ", ")))
+		}
+	}
+	printIDs("diff.depots_added", diff.DepotsAdded)
+	printIDs("diff.depots_removed", diff.DepotsRemoved)
+	for _, change := range diff.ManifestsChanged {
+		fmt.Fprintln(out, T("diff.manifest_changed", change.ID, orDefault(change.Old, "-"), orDefault(change.New, "-")))
+	}
+	for _, change := range diff.KeysChanged {
+		fmt.Fprintln(out, T("diff.key_changed", change.ID, orDefault(change.Old, "-"), orDefault(change.New, "-")))
+	}
+	printIDs("diff.dlcs_added", diff.DLCsAdded)
+	printIDs("diff.dlcs_removed", diff.DLCsRemoved)
+	return nil
+}
+
+// 参与比较的一个版本
+type diffVersion struct {
+	Name string // 文件路径或缓存内容哈希的前 12 位
+	Data []byte
+}
+
+// 缓存中 AppID 的上一个版本及当前版本
+func cachedVersions(cache *ManifestCache, appid string) (*diffVersion, *diffVersion, error) {
+	if cache == nil {
+		return nil, nil, markError(ErrInvalidInput, Err("cache.disabled"))
+	}
+	entry, err := cache.Entry(appid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry == nil {
+		return nil, nil, markError(ErrManifestNotFound, Err("cache.miss", appid))
+	}
+	if entry.PreviousSHA256 == "" {
+		return nil, nil, markError(ErrManifestNotFound, Err("diff.no_previous", appid))
+	}
+	oldData, err := cache.Object(entry.PreviousSHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+	newData, err := cache.Object(entry.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &diffVersion{Name: entry.PreviousSHA256[:12], Data: oldData}, &diffVersion{Name: entry.SHA256[:12], Data: newData}, nil
+}
+
+// 读取要比较的 Lua 文件
+func readDiffFile(path string) (*diffVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, markError(ErrInvalidInput, Err("local.read_failed", err))
+	}
+	if info.Size() > maxManifestSize {
+		return nil, markError(ErrInvalidInput, Err("local.too_large", info.Size(), maxManifestSize))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, markError(ErrInvalidInput, Err("local.read_failed", err))
+	}
+	return &diffVersion{Name: path, Data: data}, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 可翻译的哨兵错误, 消息在输出时按当前语言获取
+type sentinelError struct {
+	key string
+}
+
+func (e *sentinelError) Error() string { return T(e.key) }
+
+// 错误分类
+var (
+	ErrManifestNotFound = error(&sentinelError{"error.manifest_not_found"}) // 下载源中没有该游戏的清单
+	ErrAllSourcesFailed = error(&sentinelError{"error.all_sources_failed"}) // 所有下载源均失败
+	ErrNetwork          = error(&sentinelError{"error.network"})            // 网络错误(连接失败、超时等)
+	ErrInvalidZip       = error(&sentinelError{"error.invalid_zip"})        // ZIP 文件无效
+	ErrNoDepotKey       = error(&sentinelError{"error.no_depot_key"})       // 没有可用的 DepotKey
+	ErrInvalidInput     = error(&sentinelError{"error.invalid_input"})      // 输入或参数无效
+)
+
+// 命令行模式的退出码
+const (
+	ExitOK             = 0   // 成功
+	ExitError          = 1   // 其他错误
+	ExitInvalidInput   = 2   // 输入或参数无效
+	ExitNotFound       = 3   // 所有下载源中都没有清单
+	ExitNetwork        = 4   // 所有下载源都因网络错误失败
+	ExitAllSourcesFail = 5   // 所有下载源失败(原因不同)
+	ExitInvalidZip     = 6   // ZIP 文件无效
+	ExitNoDepotKey     = 7   // 没有可用的 DepotKey(--require-key, 或离线模式下不覆盖已下载的文件)
+	ExitCanceled       = 130 // 被 SIGINT/SIGTERM 取消
+)
+
+// 根据错误分类返回退出码
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitCanceled
+	case errors.Is(err, ErrInvalidInput):
+		return ExitInvalidInput
+	case errors.Is(err, ErrManifestNotFound):
+		return ExitNotFound
+	case errors.Is(err, ErrNetwork):
+		return ExitNetwork
+	case errors.Is(err, ErrInvalidZip):
+		return ExitInvalidZip
+	case errors.Is(err, ErrAllSourcesFailed):
+		return ExitAllSourcesFail
+	case errors.Is(err, ErrNoDepotKey):
+		return ExitNoDepotKey
+	}
+	return ExitError
+}
+
+// 为错误附加分类, 不改变错误消息
+func markError(kind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+// 带分类的错误
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// 单个下载源的尝试记录
+type SourceAttempt struct {
+	Source    string `json:"source"`           // 源编号
+	URL       string `json:"url"`              // 请求地址
+	Status    int    `json:"status,omitempty"` // HTTP 状态码, 未收到响应时为 0
+	Error     string `json:"error,omitempty"`  // 失败原因
+	LatencyMS int64  `json:"latency_ms"`       // 耗时(毫秒)
+	Err       error  `json:"-"`                // 原始错误
+}
+
+// 多个下载源都失败时的错误, 保留每个源的尝试记录
+type SourcesError struct {
+	Key      string          // 消息键
+	Attempts []SourceAttempt // 各个源的尝试记录
+}
+
+func (e *SourcesError) Error() string {
+	msgs := make([]string, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		msgs[i] = attempt.Error
+	}
+	return T(e.Key, len(e.Attempts), strings.Join(msgs, "; "))
+}
+
+// 记录失败的尝试
+func failedAttempt(source, url string, status int, start time.Time, err error) SourceAttempt {
+	return SourceAttempt{
+		Source:    source,
+		URL:       url,
+		Status:    status,
+		Error:     err.Error(),
+		LatencyMS: time.Since(start).Milliseconds(),
+		Err:       err,
+	}
+}
+
+// 输出各下载源的尝试情况
+func logSourcesReport(err error, attrs ...any) {
+	var sourcesErr *SourcesError
+	if !errors.As(err, &sourcesErr) {
+		return
+	}
+	logger.Info(T("fetch.report"), attrs...)
+	for _, attempt := range sourcesErr.Attempts {
+		status := T("fetch.report_no_status")
+		if attempt.Status != 0 {
+			status = strconv.Itoa(attempt.Status)
+		}
+		logger.Info(T("fetch.report_row", attempt.Source, attempt.URL, status, attempt.LatencyMS, attempt.Error), attrs...)
+	}
+}
+
+// 始终属于 ErrAllSourcesFailed;
+// 所有原因都是"未找到"或都是网络错误时, 分别属于 ErrManifestNotFound 或 ErrNetwork;
+// 其余分类只要任一原因匹配即可
+func (e *SourcesError) Is(target error) bool {
+	switch target {
+	case ErrAllSourcesFailed:
+		return true
+	case ErrManifestNotFound, ErrNetwork:
+		if len(e.Attempts) == 0 {
+			return false
+		}
+		for _, attempt := range e.Attempts {
+			if !errors.Is(attempt.Err, target) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, attempt := range e.Attempts {
+		if errors.Is(attempt.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// 根据状态码为错误分类, 404 视为未找到
+func statusError(err error, status int) error {
+	if status == http.StatusNotFound {
+		return markError(ErrManifestNotFound, err)
+	}
+	return err
+}
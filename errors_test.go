@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// 构造各下载源的失败记录
+func sourcesError(errs ...error) *SourcesError {
+	attempts := make([]SourceAttempt, len(errs))
+	for i, err := range errs {
+		attempts[i] = SourceAttempt{Source: fmt.Sprintf("#%d", i+1), Error: err.Error(), Err: err}
+	}
+	return &SourcesError{Key: "fetch.all_failed", Attempts: attempts}
+}
+
+func TestSourcesErrorIs(t *testing.T) {
+	notFound := statusError(errors.New("404"), http.StatusNotFound)
+	network := markError(ErrNetwork, errors.New("timeout"))
+	badZip := markError(ErrInvalidZip, errors.New("bad zip"))
+	other := errors.New("500")
+
+	tests := []struct {
+		name   string
+		err    *SourcesError
+		target error
+		want   bool
+	}{
+		{"always all sources failed", sourcesError(other), ErrAllSourcesFailed, true},
+		{"no attempts is not not-found", sourcesError(), ErrManifestNotFound, false},
+		{"all not found", sourcesError(notFound, notFound), ErrManifestNotFound, true},
+		{"not found needs every attempt", sourcesError(notFound, network), ErrManifestNotFound, false},
+		{"all network", sourcesError(network, network), ErrNetwork, true},
+		{"network needs every attempt", sourcesError(network, other), ErrNetwork, false},
+		{"invalid zip from any attempt", sourcesError(notFound, badZip, network), ErrInvalidZip, true},
+		{"invalid zip absent", sourcesError(notFound, network), ErrInvalidZip, false},
+		{"canceled from any attempt", sourcesError(network, context.Canceled), context.Canceled, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	notFound := statusError(errors.New("404"), http.StatusNotFound)
+	network := markError(ErrNetwork, errors.New("timeout"))
+	badZip := markError(ErrInvalidZip, errors.New("bad zip"))
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"plain error", errors.New("boom"), ExitError},
+		{"canceled", Err("cancel.canceled", context.Canceled), ExitCanceled},
+		{"invalid input", markError(ErrInvalidInput, errors.New("bad")), ExitInvalidInput},
+		{"no depot key", markError(ErrNoDepotKey, errors.New("no key")), ExitNoDepotKey},
+		{"all sources not found", sourcesError(notFound, notFound), ExitNotFound},
+		{"all sources network", sourcesError(network, network), ExitNetwork},
+		{"mixed reasons", sourcesError(notFound, network), ExitAllSourcesFail},
+		{"any invalid zip", sourcesError(notFound, badZip), ExitInvalidZip},
+		{"wrapped sources error", fmt.Errorf("download: %w", sourcesError(notFound)), ExitNotFound},
+		{"canceled during sources", sourcesError(network, context.Canceled), ExitCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
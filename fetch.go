@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 各类响应体的大小上限
+const (
+	maxManifestSize = 16 << 20  // 单个清单文件
+	maxJSONSize     = 64 << 20  // depotkeys.json 及各类 API 响应
+	maxIndexSize    = 256 << 20 // Steam 应用列表
+	maxDrainSize    = 64 << 10  // 非 200 响应最多读取并丢弃的字节数, 超过时直接断开连接
+)
+
+// 单次请求的响应
+type fetchResponse struct {
+	Status int         // HTTP 状态码
+	Header http.Header // 响应头
+	Body   []byte      // 响应体, 状态码不是 200 时为空
+}
+
+// 发送 GET 请求并读取响应体, 返回前释放超时上下文和连接
+// timeout 为 0 时只受 ctx 和 client 的超时限制; 响应体超过 limit 字节时返回错误;
+// 状态码不是 200 时不返回错误, 由调用方根据 Status 处理, 响应体被读尽丢弃以便复用连接
+func fetch(ctx context.Context, client *http.Client, url string, timeout time.Duration, limit int64) (*fetchResponse, error) {
+	return fetchWithHeader(ctx, client, url, nil, timeout, limit)
+}
+
+// 同 fetch, 请求时附加额外的请求头(如条件请求的 If-None-Match)
+func fetchWithHeader(ctx context.Context, client *http.Client, url string, header http.Header, timeout time.Duration, limit int64) (*fetchResponse, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, Err("http.new_request_failed", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, markError(ErrNetwork, Err("http.request_failed", err))
+	}
+	defer resp.Body.Close()
+
+	result := &fetchResponse{Status: resp.StatusCode, Header: resp.Header}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+		return result, nil
+	}
+
+	// 多读一个字节用于判断是否超过上限
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return result, markError(ErrNetwork, Err("http.read_failed", err))
+	}
+	if int64(len(body)) > limit {
+		return result, Err("http.too_large", limit)
+	}
+	result.Body = body
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// 按路径返回指定状态码和大小的响应: /<状态码>/<字节数>
+func newFetchServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var status, size int
+		if _, err := fmt.Sscanf(r.URL.Path, "/%d/%d", &status, &size); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("X-If-None-Match", r.Header.Get("If-None-Match"))
+		w.WriteHeader(status)
+		w.Write(bytes.Repeat([]byte("a"), size))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+func TestFetchLimit(t *testing.T) {
+	server, _ := newFetchServer(t)
+	const limit = 1024
+	tests := []struct {
+		name     string
+		path     string
+		wantErr  string
+		wantBody int
+	}{
+		{"within limit", "/200/100", "", 100},
+		{"exactly limit", "/200/1024", "", limit},
+		{"one byte over", "/200/1025", Err("http.too_large", limit).Error(), 0},
+		{"far over", "/200/1048576", Err("http.too_large", limit).Error(), 0},
+		{"not found body dropped", "/404/100", "", 0},
+		{"error body over limit not an error", "/500/1048576", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := fetch(context.Background(), http.DefaultClient, server.URL+tt.path, 0, limit)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("fetch error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			wantStatus, _ := strconv.Atoi(tt.path[1:4])
+			if resp.Status != wantStatus || len(resp.Body) != tt.wantBody {
+				t.Errorf("fetch = status %d, %d bytes; want %d, %d bytes", resp.Status, len(resp.Body), wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+// 非 200 的响应体不超过 maxDrainSize 时读尽以复用连接, 超过时断开连接而不是读完
+func TestFetchDrain(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		wantConns int32
+	}{
+		{"small body drained and connection reused", 100, 1},
+		{"large body not drained", maxDrainSize * 16, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, conns := newFetchServer(t)
+			client := &http.Client{Transport: &http.Transport{}}
+			defer client.CloseIdleConnections()
+			for range 2 {
+				resp, err := fetch(context.Background(), client, server.URL+"/404/"+strconv.Itoa(tt.size), 0, maxManifestSize)
+				if err != nil || resp.Status != http.StatusNotFound || len(resp.Body) != 0 {
+					t.Fatalf("fetch = %+v, %v; want 404 with empty body", resp, err)
+				}
+			}
+			if got := conns.Load(); got != tt.wantConns {
+				t.Errorf("connections = %d, want %d", got, tt.wantConns)
+			}
+		})
+	}
+}
+
+func TestFetchWithHeader(t *testing.T) {
+	server, _ := newFetchServer(t)
+	header := http.Header{"If-None-Match": {`"v1"`}}
+	resp, err := fetchWithHeader(context.Background(), http.DefaultClient, server.URL+"/304/0", header, 0, maxManifestSize)
+	if err != nil || resp.Status != http.StatusNotModified || resp.Header.Get("X-If-None-Match") != `"v1"` {
+		t.Fatalf("fetchWithHeader = %+v, %v; want 304 with the request header echoed", resp, err)
+	}
+}
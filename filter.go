@@ -0,0 +1,108 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 创建空的DLC过滤规则
+func NewDLCFilter() *DLCFilter {
+	return &DLCFilter{
+		ExcludeTypes: make(map[string]bool),
+		Allow:        make(map[string]map[string]bool),
+		Deny:         make(map[string]map[string]bool),
+	}
+}
+
+// 设置排除的DLC类型(逗号分隔)
+func (f *DLCFilter) SetExcludeTypes(value string) {
+	f.ExcludeTypes = make(map[string]bool)
+	for _, t := range splitList(value) {
+		f.ExcludeTypes[strings.ToLower(t)] = true
+	}
+}
+
+// 设置按名称排除的正则表达式
+func (f *DLCFilter) SetExcludeName(pattern string) error {
+	if pattern == "" {
+		f.ExcludeName = nil
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Err("filter.bad_regex", err)
+	}
+	f.ExcludeName = re
+	return nil
+}
+
+// 设置某个游戏的DLC白名单/黑名单(逗号分隔的AppID)
+func (f *DLCFilter) SetList(list map[string]map[string]bool, appid, value string) {
+	ids := make(map[string]bool)
+	for _, id := range splitList(value) {
+		ids[id] = true
+	}
+	list[appid] = ids
+}
+
+// 设置信息源未提供仓库信息的DLC的处理方式: add 添加 / skip 跳过
+func (f *DLCFilter) SetUnknownDepots(value string) error {
+	switch strings.ToLower(value) {
+	case "add":
+		f.AddUnknown = true
+	case "skip":
+		f.AddUnknown = false
+	default:
+		return Err("filter.bad_unknown_depots", value)
+	}
+	return nil
+}
+
+// 是否添加仓库信息未知的DLC, 未设置规则时跳过
+func (f *DLCFilter) addsUnknown() bool {
+	return f != nil && f.AddUnknown
+}
+
+// 判断是否保留DLC, 返回是否保留及原因
+func (f *DLCFilter) Decide(appid string, dlc *AppDetail) (bool, string) {
+	if f == nil {
+		return true, T("filter.no_rules")
+	}
+
+	// 黑名单优先
+	if f.Deny[appid][dlc.AppID] {
+		return false, T("filter.denied")
+	}
+
+	// 白名单内的DLC不再检查类型和名称
+	if allow, ok := f.Allow[appid]; ok && len(allow) > 0 {
+		if allow[dlc.AppID] {
+			return true, T("filter.allowed")
+		}
+		return false, T("filter.not_allowed")
+	}
+
+	// 按类型排除
+	if dlc.Type != "" && f.ExcludeTypes[strings.ToLower(dlc.Type)] {
+		return false, T("filter.type_excluded", dlc.Type)
+	}
+
+	// 按名称排除
+	if f.ExcludeName != nil && dlc.Name != "" && f.ExcludeName.MatchString(dlc.Name) {
+		return false, T("filter.name_excluded", f.ExcludeName.String())
+	}
+
+	return true, T("filter.passed")
+}
+
+// 拆分逗号分隔的列表, 忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestDLCFilterDecide(t *testing.T) {
+	filter := NewDLCFilter()
+	filter.SetExcludeTypes("Music, video")
+	if err := filter.SetExcludeName(`(?i)soundtrack|artbook`); err != nil {
+		t.Fatal(err)
+	}
+	filter.SetList(filter.Allow, "100", "101, 102")
+	filter.SetList(filter.Deny, "100", "102")
+	filter.SetList(filter.Deny, "200", "201")
+
+	tests := []struct {
+		name   string
+		filter *DLCFilter
+		appid  string
+		dlc    AppDetail
+		keep   bool
+		reason string
+	}{
+		{"no rules", nil, "300", AppDetail{AppID: "301", Type: "music"}, true, T("filter.no_rules")},
+		{"deny wins over allow", filter, "100", AppDetail{AppID: "102"}, false, T("filter.denied")},
+		{"allowed skips type and name", filter, "100", AppDetail{AppID: "101", Type: "music", Name: "Soundtrack"}, true, T("filter.allowed")},
+		{"not in allow list", filter, "100", AppDetail{AppID: "103"}, false, T("filter.not_allowed")},
+		{"denied", filter, "200", AppDetail{AppID: "201"}, false, T("filter.denied")},
+		{"type excluded case-insensitively", filter, "200", AppDetail{AppID: "202", Type: "Music"}, false, T("filter.type_excluded", "Music")},
+		{"name excluded", filter, "200", AppDetail{AppID: "203", Type: "dlc", Name: "Digital Artbook"}, false, T("filter.name_excluded", `(?i)soundtrack|artbook`)},
+		{"unknown type and name pass", filter, "200", AppDetail{AppID: "204"}, true, T("filter.passed")},
+		{"passed", filter, "200", AppDetail{AppID: "205", Type: "dlc", Name: "Expansion Pass"}, true, T("filter.passed")},
+		{"deny list of another game ignored", filter, "300", AppDetail{AppID: "201"}, true, T("filter.passed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, reason := tt.filter.Decide(tt.appid, &tt.dlc)
+			if keep != tt.keep || reason != tt.reason {
+				t.Errorf("Decide(%s, %s) = %v, %q; want %v, %q", tt.appid, tt.dlc.AppID, keep, reason, tt.keep, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSetExcludeNameInvalid(t *testing.T) {
+	filter := NewDLCFilter()
+	if err := filter.SetExcludeName("("); err == nil {
+		t.Fatal("SetExcludeName accepted an invalid regular expression")
+	}
+	if err := filter.SetExcludeName(""); err != nil || filter.ExcludeName != nil {
+		t.Fatalf("SetExcludeName(\"\") = %v, ExcludeName = %v; want rule cleared", err, filter.ExcludeName)
+	}
+}
+
+func TestSetUnknownDepots(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"add", true, false},
+		{"ADD", true, false},
+		{"skip", false, false},
+		{"yes", false, true},
+	}
+	for _, tt := range tests {
+		filter := NewDLCFilter()
+		err := filter.SetUnknownDepots(tt.value)
+		if (err != nil) != tt.wantErr || filter.AddUnknown != tt.want {
+			t.Errorf("SetUnknownDepots(%q) = %v, AddUnknown = %v; want %v, error %v", tt.value, err, filter.AddUnknown, tt.want, tt.wantErr)
+		}
+	}
+	var filter *DLCFilter
+	if filter.addsUnknown() {
+		t.Error("nil filter adds DLCs with unknown depots")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// ManifestHub 每个 AppID 一个分支, 依次尝试本地分支和 origin 的远程分支
+// 使用完整引用名, 避免纯数字的分支名被当作提交哈希的缩写
+func gitRefs(appid string) []string {
+	return []string{"refs/heads/" + appid, "refs/remotes/origin/" + appid}
+}
+
+// 从本地仓库(普通或裸仓库)读取 <AppID>.lua, 通过 git 命令读取, 不修改工作区
+func ReadGitSource(ctx context.Context, repo, appid string) ([]byte, error) {
+	if _, err := runGit(ctx, repo, "rev-parse", "--git-dir"); err != nil {
+		return nil, Err("git.not_repo", repo, err)
+	}
+
+	for _, ref := range gitRefs(appid) {
+		// 分支不存在时尝试下一个
+		if _, err := runGit(ctx, repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+			if ctx.Err() != nil {
+				return nil, Err("cancel.canceled", ctx.Err())
+			}
+			continue
+		}
+
+		data, err := runGit(ctx, repo, "cat-file", "blob", ref+":"+appid+".lua")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, Err("cancel.canceled", ctx.Err())
+			}
+			logVerbose(T("git.no_file", ref, appid), "appid", appid, "source", repo, "step", "fetch")
+			continue
+		}
+		if len(data) > maxManifestSize {
+			return nil, Err("local.too_large", len(data), maxManifestSize)
+		}
+		logVerbose(T("git.read", ref), "appid", appid, "source", repo, "step", "fetch")
+		return data, nil
+	}
+	return nil, markError(ErrManifestNotFound, Err("git.not_found", appid, repo))
+}
+
+// 检查本地仓库中是否有该 AppID 的清单; 仓库无效或无法执行 git 时无法确定
+func gitAvailability(ctx context.Context, repo, appid string) Availability {
+	if _, err := runGit(ctx, repo, "rev-parse", "--git-dir"); err != nil {
+		return AvailabilityUnknown
+	}
+	for _, ref := range gitRefs(appid) {
+		if _, err := runGit(ctx, repo, "cat-file", "-e", ref+":"+appid+".lua"); err == nil {
+			return AvailabilityAvailable
+		}
+	}
+	if ctx.Err() != nil {
+		return AvailabilityUnknown
+	}
+	return AvailabilityMissing
+}
+
+// 在仓库中执行 git 命令, 返回标准输出; 失败时错误中包含 git 的错误信息
+func runGit(ctx context.Context, repo string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, Err("git.not_installed", err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 默认语言, 缺少的翻译也从该语言包获取
+const defaultLanguage = "zh-CN"
+
+// 各语言的消息目录
+var catalogs = map[string]map[string]string{
+	"zh-CN": catalogZH,
+	"en":    catalogEN,
+}
+
+// 当前语言
+var language = defaultLanguage
+
+// 设置当前语言
+func SetLanguage(lang string) error {
+	normalized, ok := normalizeLanguage(lang)
+	if !ok {
+		return Err("i18n.bad_language", lang)
+	}
+	language = normalized
+	return nil
+}
+
+// 选择语言: 环境变量 MANIFESTHUB_LANG > 配置文件 > 系统区域设置 > 默认中文
+func DetectLanguage(configured string) string {
+	if lang := os.Getenv("MANIFESTHUB_LANG"); lang != "" {
+		return lang
+	}
+	if configured != "" {
+		return configured
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		// 忽略不支持的区域设置(如 C、POSIX)
+		if value := os.Getenv(name); value != "" {
+			if _, ok := normalizeLanguage(value); ok {
+				return value
+			}
+		}
+	}
+	return defaultLanguage
+}
+
+// 规范化语言名称(如 zh_CN.UTF-8 -> zh-CN, en_US -> en)
+func normalizeLanguage(lang string) (string, bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, ".@"); i >= 0 {
+		lang = lang[:i]
+	}
+	switch {
+	case strings.HasPrefix(lang, "zh"):
+		return "zh-CN", true
+	case strings.HasPrefix(lang, "en"):
+		return "en", true
+	}
+	return "", false
+}
+
+// 查找消息, 当前语言缺失时使用默认语言, 都缺失时返回键名
+func message(key string) string {
+	if msg, ok := catalogs[language][key]; ok {
+		return msg
+	}
+	if msg, ok := catalogs[defaultLanguage][key]; ok {
+		return msg
+	}
+	return key
+}
+
+// 获取翻译后的消息, 有参数时按格式化字符串处理
+func T(key string, args ...any) string {
+	if len(args) == 0 {
+		return message(key)
+	}
+	return fmt.Sprintf(message(key), args...)
+}
+
+// 创建翻译后的错误
+func Err(key string, args ...any) error {
+	if len(args) == 0 {
+		return errors.New(message(key))
+	}
+	return fmt.Errorf(message(key), args...)
+}
+
+// 当前语言中以 prefix 开头的所有消息, 缺失的翻译使用默认语言
+func Messages(prefix string) map[string]string {
+	messages := make(map[string]string)
+	for key := range catalogs[defaultLanguage] {
+		if strings.HasPrefix(key, prefix) {
+			messages[key] = message(key)
+		}
+	}
+	return messages
+}
+
+// 检查每个键是否存在于所有语言包中, 返回缺失项
+func CheckCatalogs() []string {
+	keys := make(map[string]bool)
+	for _, catalog := range catalogs {
+		for key := range catalog {
+			keys[key] = true
+		}
+	}
+
+	var missing []string
+	for lang, catalog := range catalogs {
+		for key := range keys {
+			if _, ok := catalog[key]; !ok {
+				missing = append(missing, T("i18n.missing", lang, key))
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// 检查语言包命令
+func RunI18nCheck() error {
+	missing := CheckCatalogs()
+	for _, m := range missing {
+		logger.Warn(m)
+	}
+	if len(missing) > 0 {
+		return Err("i18n.missing_count", len(missing))
+	}
+	logger.Info(T("i18n.ok", len(catalogs[defaultLanguage])))
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// 格式化动词, 如 %s、%-30s、%.1f、%w、%[2]s
+var formatVerbRegex = regexp.MustCompile(`%[-+# 0]*\d*(?:\.\d+)?(?:\[(\d+)\])?([a-zA-Z%])`)
+
+// 消息中各参数使用的格式化动词(不含 %%), 如 1s、2d, 按参数序号排列
+// 带序号的动词(%[2]s)可以在翻译中调整顺序, 因此按参数而不是出现的位置比较
+func formatVerbs(msg string) []string {
+	type verb struct {
+		arg  int
+		name string
+	}
+	var verbs []verb
+	arg := 0
+	for _, m := range formatVerbRegex.FindAllStringSubmatch(msg, -1) {
+		if m[2] == "%" {
+			continue
+		}
+		// 没有序号时使用上一个参数的下一个
+		if m[1] != "" {
+			arg, _ = strconv.Atoi(m[1])
+		} else {
+			arg++
+		}
+		verbs = append(verbs, verb{arg, m[2]})
+	}
+	slices.SortStableFunc(verbs, func(a, b verb) int { return a.arg - b.arg })
+
+	var names []string
+	for _, v := range verbs {
+		names = append(names, strconv.Itoa(v.arg)+v.name)
+	}
+	return names
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, missing := range CheckCatalogs() {
+		t.Error(missing)
+	}
+}
+
+// 各语言的同一消息必须有相同数量、相同顺序的格式化动词, 否则翻译后参数错位
+func TestCatalogFormatVerbs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		if lang == defaultLanguage {
+			continue
+		}
+		for key, want := range catalogs[defaultLanguage] {
+			got, ok := catalog[key]
+			if !ok {
+				continue
+			}
+			if wantVerbs, gotVerbs := formatVerbs(want), formatVerbs(got); !slices.Equal(wantVerbs, gotVerbs) {
+				t.Errorf("%s %s: verbs %v, %s has %v", lang, key, gotVerbs, defaultLanguage, wantVerbs)
+			}
+		}
+	}
+}
+
+func TestFormatVerbs(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want []string
+	}{
+		{"no verbs", nil},
+		{"%s (AppID: %d)", []string{"1s", "2d"}},
+		{" %d. [%s] %-30s | AppID: %-8d", []string{"1d", "2s", "3s", "4d"}},
+		{"100%% done in %.1fs: %w", []string{"1f", "2w"}},
+		{"found %[1]d games in %[2]s", []string{"1d", "2s"}},
+		{"%[2]s 中有 %[1]d 个游戏", []string{"1d", "2s"}},
+		{"%[2]s then %s", []string{"2s", "3s"}},
+		{"%-10[1]s|%[1]q", []string{"1s", "1q"}},
+	}
+	for _, tt := range tests {
+		if got := formatVerbs(tt.msg); !slices.Equal(got, tt.want) {
+			t.Errorf("formatVerbs(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
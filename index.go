@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 本地索引最多返回的结果数
+const maxLocalResults = 50
+
+// 已加载的本地索引(按路径缓存), serve 模式下可能被并发访问
+var (
+	loadedIndex   = map[string]*AppIndex{}
+	loadedIndexMu sync.Mutex
+)
+
+// 下载并刷新本地搜索索引
+func UpdateIndex(ctx context.Context, config *Config) error {
+	logger.Info(T("index.downloading", AppListURL), "source", AppListURL, "step", "index")
+
+	// 应用列表较大, 使用单独的超时
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := fetch(ctx, client, AppListURL, 0, maxIndexSize)
+	if err != nil {
+		return Err("index.download_failed", err)
+	}
+	if resp.Status != http.StatusOK {
+		return Err("index.bad_status", resp.Status)
+	}
+
+	var response AppListResponse
+	if err := json.Unmarshal(resp.Body, &response); err != nil {
+		return Err("index.parse_failed", err)
+	}
+
+	// 过滤无名称的条目
+	index := &AppIndex{Updated: time.Now()}
+	for _, app := range response.AppList.Apps {
+		if strings.TrimSpace(app.Name) != "" {
+			index.Apps = append(index.Apps, app)
+		}
+	}
+	if len(index.Apps) == 0 {
+		return Err("index.empty")
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return Err("index.marshal_failed", err)
+	}
+
+	// 先写临时文件再替换, 避免中断时损坏原索引
+	if dir := filepath.Dir(config.IndexPath); dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return Err("fs.mkdir_failed", err)
+		}
+	}
+	tmpPath := config.IndexPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return Err("index.save_failed", err)
+	}
+	if err := os.Rename(tmpPath, config.IndexPath); err != nil {
+		os.Remove(tmpPath)
+		return Err("index.save_failed", err)
+	}
+
+	loadedIndexMu.Lock()
+	loadedIndex[config.IndexPath] = index
+	loadedIndexMu.Unlock()
+	logger.Info(T("index.updated", config.IndexPath, len(index.Apps)), "step", "index")
+	return nil
+}
+
+// 读取本地搜索索引
+func LoadIndex(path string) (*AppIndex, error) {
+	loadedIndexMu.Lock()
+	defer loadedIndexMu.Unlock()
+	if index, ok := loadedIndex[path]; ok {
+		return index, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, Err("index.missing", path)
+		}
+		return nil, Err("index.read_failed", err)
+	}
+
+	var index AppIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, Err("index.load_parse_failed", err)
+	}
+
+	loadedIndex[path] = &index
+	return &index, nil
+}
+
+// 在本地索引中模糊搜索游戏
+func SearchLocal(config *Config, gameName string) ([]Game, error) {
+	index, err := LoadIndex(config.IndexPath)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(T("index.searching", gameName, index.Updated.Format("2006-01-02 15:04")), "step", "search")
+
+	queryTokens := tokenize(gameName)
+	if len(queryTokens) == 0 {
+		return nil, nil
+	}
+
+	var games []Game
+	for _, app := range index.Apps {
+		if fuzzyMatch(queryTokens, tokenize(app.Name)) {
+			games = append(games, app)
+		}
+	}
+
+	// 按匹配程度排序
+	games = RankGames(gameName, games)
+	if len(games) > maxLocalResults {
+		games = games[:maxLocalResults]
+	}
+	return games, nil
+}
+
+// 每个查询词元都包含于名称词元中, 或与某个名称词元仅差一个字符
+func fuzzyMatch(queryTokens, nameTokens []string) bool {
+	for _, q := range queryTokens {
+		matched := false
+		for _, n := range nameTokens {
+			if strings.Contains(n, q) {
+				matched = true
+				break
+			}
+			if len([]rune(q)) >= 4 && levenshtein(q, n) <= 1 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
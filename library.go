@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 下载库文件, 保存在下载路径中
+const libraryFile = "library.json"
+
+// 下载库中单个游戏的记录
+type LibraryEntry struct {
+	AppID            string    `json:"appid"`
+	Name             string    `json:"name,omitempty"`     // 游戏名称, 获取失败时为空
+	DownloadedAt     time.Time `json:"downloaded_at"`      // 最近一次下载的时间
+	Source           string    `json:"source"`             // 下载源或本地文件
+	DepotKeysPatched int       `json:"depot_keys_patched"` // 修补的 DepotKey 数量
+	DLCCount         int       `json:"dlc_count"`          // 添加的DLC数量
+	SHA256           string    `json:"sha256"`             // 保存的 <AppID>.lua 的哈希
+}
+
+// 下载库文件的内容
+type Library struct {
+	Apps []LibraryEntry `json:"apps"`
+}
+
+// 同一进程内对下载库的读写互斥
+var libraryMu sync.Mutex
+
+// 读取下载路径中的下载库, 文件不存在时返回空库
+func LoadLibrary(dir string) (*Library, error) {
+	data, err := os.ReadFile(filepath.Join(dir, libraryFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Library{}, nil
+	}
+	if err != nil {
+		return nil, Err("library.read_failed", err)
+	}
+	var library Library
+	if err := json.Unmarshal(data, &library); err != nil {
+		return nil, Err("library.read_failed", err)
+	}
+	return &library, nil
+}
+
+// 保存下载库, 条目按 AppID 排序
+func (l *Library) Save(dir string) error {
+	sort.Slice(l.Apps, func(i, j int) bool { return appIDLess(l.Apps[i].AppID, l.Apps[j].AppID) })
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return Err("library.write_failed", err)
+	}
+	return writeFileAtomic(filepath.Join(dir, libraryFile), data)
+}
+
+// AppID 的记录, 不存在时返回 nil
+func (l *Library) Get(appid string) *LibraryEntry {
+	for i := range l.Apps {
+		if l.Apps[i].AppID == appid {
+			return &l.Apps[i]
+		}
+	}
+	return nil
+}
+
+// 添加或替换 AppID 的记录
+func (l *Library) Put(entry LibraryEntry) {
+	for i := range l.Apps {
+		if l.Apps[i].AppID == entry.AppID {
+			l.Apps[i] = entry
+			return
+		}
+	}
+	l.Apps = append(l.Apps, entry)
+}
+
+// 把成功的下载记录到下载库
+func RecordDownload(dir string, result *DownloadResult) error {
+	data, err := os.ReadFile(result.OutputPath)
+	if err != nil {
+		return Err("library.read_failed", err)
+	}
+
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+	library, err := LoadLibrary(dir)
+	if err != nil {
+		return err
+	}
+	// 本次未能获取名称(如离线模式)时保留之前记录的名称
+	name := result.Name
+	if previous := library.Get(result.AppID); previous != nil && name == "" {
+		name = previous.Name
+	}
+	library.Put(LibraryEntry{
+		AppID:            result.AppID,
+		Name:             name,
+		DownloadedAt:     time.Now(),
+		Source:           result.Source,
+		DepotKeysPatched: result.DepotKeysPatched,
+		DLCCount:         len(result.DLCsAdded),
+		SHA256:           sha256Hex(data),
+	})
+	return library.Save(dir)
+}
+
+// 执行 list 命令: 按条件筛选并排序下载库中的记录
+func RunList(config *Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	sortBy := flags.String("sort", "appid", "")
+	desc := flags.Bool("desc", false, "")
+	filter := flags.String("filter", "", "")
+	source := flags.String("source", "", "")
+	if err := flags.Parse(args); err != nil {
+		return usageError(Err("library.bad_args", err))
+	}
+
+	less, ok := librarySorters[*sortBy]
+	if !ok {
+		return markError(ErrInvalidInput, Err("library.bad_sort", *sortBy))
+	}
+
+	library, err := LoadLibrary(config.DownloadPath)
+	if err != nil {
+		return err
+	}
+
+	// 按 AppID/名称及下载源筛选(不区分大小写)
+	keyword := strings.ToLower(*filter)
+	entries := []LibraryEntry{}
+	for _, entry := range library.Apps {
+		if keyword != "" && !strings.Contains(entry.AppID, keyword) && !strings.Contains(strings.ToLower(entry.Name), keyword) {
+			continue
+		}
+		if *source != "" && !strings.Contains(strings.ToLower(entry.Source), strings.ToLower(*source)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if *desc {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+
+	if config.Output != OutputText {
+		return writeRecords(out, config.Output, entries)
+	}
+
+	fmt.Fprintln(out, Division)
+	for _, entry := range entries {
+		fmt.Fprintf(out, "%-10s %-40s %s  %s  %s\n",
+			entry.AppID, entry.Name, entry.DownloadedAt.Local().Format(time.DateTime),
+			T("library.counts", entry.DLCCount, entry.DepotKeysPatched), entry.Source)
+	}
+	fmt.Fprintln(out, T("library.total", len(entries), len(library.Apps)))
+	return nil
+}
+
+// list 命令支持的排序方式
+var librarySorters = map[string]func(a, b LibraryEntry) bool{
+	"appid": func(a, b LibraryEntry) bool { return appIDLess(a.AppID, b.AppID) },
+	"name":  func(a, b LibraryEntry) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+	"time":  func(a, b LibraryEntry) bool { return a.DownloadedAt.Before(b.DownloadedAt) },
+	"dlc":   func(a, b LibraryEntry) bool { return a.DLCCount < b.DLCCount },
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunList(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	library := &Library{Apps: []LibraryEntry{
+		{AppID: "730", Name: "Counter-Strike 2", DownloadedAt: now, Source: "https://a.example", DLCCount: 0},
+		{AppID: "10", Name: "Counter-Strike", DownloadedAt: now.Add(-time.Hour), Source: "https://b.example", DLCCount: 3},
+		{AppID: "1245620", Name: "ELDEN RING", DownloadedAt: now.Add(-2 * time.Hour), Source: "https://a.example", DLCCount: 1},
+	}}
+	if err := library.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string // 输出的 AppID 顺序
+	}{
+		{"default sort by appid", nil, []string{"10", "730", "1245620"}},
+		{"sort by time descending", []string{"--sort", "time", "--desc"}, []string{"730", "10", "1245620"}},
+		{"sort by dlc", []string{"--sort", "dlc"}, []string{"730", "1245620", "10"}},
+		{"filter by name", []string{"--filter", "counter"}, []string{"10", "730"}},
+		{"filter by source", []string{"--source", "A.EXAMPLE"}, []string{"730", "1245620"}},
+		{"no match", []string{"--filter", "portal"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			config := &Config{DownloadPath: dir, Output: OutputNDJSON}
+			if err := RunList(config, tt.args, &out); err != nil {
+				t.Fatal(err)
+			}
+			if n := ndjsonLines(t, out.String()); n != len(tt.want) {
+				t.Fatalf("RunList wrote %d records, want %d:\n%s", n, len(tt.want), out.String())
+			}
+			var got []string
+			decoder := json.NewDecoder(&out)
+			for decoder.More() {
+				var entry LibraryEntry
+				if err := decoder.Decode(&entry); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, entry.AppID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RunList = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := RunList(&Config{DownloadPath: dir}, []string{"--sort", "size"}, &bytes.Buffer{}); ExitCode(err) != ExitInvalidInput {
+		t.Errorf("RunList with unknown sort: exit code %d, want %d", ExitCode(err), ExitInvalidInput)
+	}
+}
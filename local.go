@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 文件名开头的 AppID(如 "730.zip"、"730 (1).lua")
+var localAppIDRegex = regexp.MustCompile(`^(\d+)`)
+
+// 压缩包中的 <AppID>.lua
+var luaEntryRegex = regexp.MustCompile(`^(\d+)\.lua$`)
+
+// 判断输入是否为本地 ZIP/Lua 文件(路径或 file:// URL), 返回文件路径
+func LocalPath(input string) (string, bool) {
+	// 拖入控制台的路径可能带有引号
+	input = strings.Trim(strings.TrimSpace(input), `"'`)
+	if input == "" {
+		return "", false
+	}
+
+	if strings.HasPrefix(strings.ToLower(input), "file://") {
+		u, err := url.Parse(input)
+		if err != nil {
+			return "", false
+		}
+		path := u.Path
+		// Windows 下 file:///C:/x 解析为 /C:/x
+		if runtime.GOOS == "windows" && len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+			path = path[1:]
+		}
+		if !isLocalFileType(path) {
+			return "", false
+		}
+		return filepath.FromSlash(path), true
+	}
+
+	if !isLocalFileType(input) {
+		return "", false
+	}
+	if info, err := os.Stat(input); err != nil || info.IsDir() {
+		return "", false
+	}
+	return input, true
+}
+
+// 是否为支持的本地文件类型(.zip 或 .lua)
+func isLocalFileType(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip", ".lua":
+		return true
+	}
+	return false
+}
+
+// 根据文件名确定 AppID; 文件名中没有时, 使用压缩包中唯一的 <AppID>.lua
+func localAppID(path string) (string, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if m := localAppIDRegex.FindStringSubmatch(name); m != nil {
+		return m[1], nil
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		if zipReader, err := zip.OpenReader(path); err == nil {
+			defer zipReader.Close()
+			var found []string
+			for _, file := range zipReader.File {
+				if m := luaEntryRegex.FindStringSubmatch(zipBaseName(file.Name)); m != nil {
+					found = append(found, m[1])
+				}
+			}
+			if len(found) == 1 {
+				return found[0], nil
+			}
+		}
+	}
+	return "", markError(ErrInvalidInput, Err("local.no_appid", path))
+}
+
+// 读取本地的 ZIP 或 Lua 文件, 代替从下载源获取
+func LoadLocalFile(ctx context.Context, path, APPID string) (*FetchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, Err("cancel.canceled", err)
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	logger.Info(T("local.reading", path), "appid", APPID, "source", path, "step", "fetch")
+	start := time.Now()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, markError(ErrInvalidInput, Err("local.read_failed", err))
+	}
+
+	data, archive, err := readLocalFile(path, info, APPID)
+	if err != nil {
+		return nil, err
+	}
+	attempts := []SourceAttempt{{Source: "local", URL: path, LatencyMS: time.Since(start).Milliseconds()}}
+	return &FetchResult{Data: data, Source: path, Archive: archive, Attempts: attempts}, nil
+}
+
+// 读取本地的 Lua 文件或 ZIP 中的 <AppID>.lua, 为 ZIP 时同时返回压缩包路径
+func readLocalFile(path string, info os.FileInfo, APPID string) ([]byte, string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		// 压缩包同样受大小限制
+		if ZipLimits.MaxArchiveSize > 0 && info.Size() > ZipLimits.MaxArchiveSize {
+			return nil, "", markError(ErrInvalidZip, Err("zip.archive_too_large", info.Size(), ZipLimits.MaxArchiveSize))
+		}
+		data, err := extractLua(path, APPID, path)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, path, nil
+	}
+
+	if info.Size() > maxManifestSize {
+		return nil, "", markError(ErrInvalidInput, Err("local.too_large", info.Size(), maxManifestSize))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", markError(ErrInvalidInput, Err("local.read_failed", err))
+	}
+	printDivision()
+	return data, "", nil
+}
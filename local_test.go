@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	dir := t.TempDir()
+	lua := filepath.Join(dir, "730 (1).lua")
+	archive := filepath.Join(dir, "manifest.ZIP")
+	for _, path := range []string{lua, archive, filepath.Join(dir, "notes.txt")} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "folder.lua"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Windows 下去掉盘符前的斜杠
+	windowsPath := "/C:/Games/730.lua"
+	if runtime.GOOS == "windows" {
+		windowsPath = `C:\Games\730.lua`
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"empty", "  ", "", false},
+		{"appid", "730", "", false},
+		{"store link", "https://store.steampowered.com/app/730/", "", false},
+		{"existing lua", lua, lua, true},
+		{"existing zip with upper-case extension", archive, archive, true},
+		{"double-quoted drag and drop", ` "` + lua + `" `, lua, true},
+		{"single-quoted drag and drop", `'` + archive + `'`, archive, true},
+		{"missing file", filepath.Join(dir, "10.lua"), "", false},
+		{"directory", filepath.Join(dir, "folder.lua"), "", false},
+		{"other extension", filepath.Join(dir, "notes.txt"), "", false},
+		{"file url", "file:///home/user/730.lua", filepath.FromSlash("/home/user/730.lua"), true},
+		{"file url upper-case scheme", "FILE:///tmp/1245620.zip", filepath.FromSlash("/tmp/1245620.zip"), true},
+		{"file url with escaped space", "file:///home/user/My%20Games/730.lua", filepath.FromSlash("/home/user/My Games/730.lua"), true},
+		{"file url with drive letter", "file:///C:/Games/730.lua", windowsPath, true},
+		{"file url other extension", "file:///x.txt", "", false},
+		{"file url without extension", "file:///home/user/730", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LocalPath(tt.input)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("LocalPath(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLocalAppID(t *testing.T) {
+	dir := t.TempDir()
+	writeZip := func(name string, entries map[string][]byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, makeZipEntries(t, entries), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	single := writeZip("manifest.zip", map[string][]byte{"repo/123.lua": nil, "repo/123_456.manifest": nil, "readme.txt": nil})
+	multiple := writeZip("bundle.zip", map[string][]byte{"123.lua": nil, "456.lua": nil})
+	none := writeZip("empty.zip", map[string][]byte{"readme.txt": nil})
+	named := writeZip("789.zip", map[string][]byte{"123.lua": nil})
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"lua name", "/downloads/730.lua", "730"},
+		{"copy suffix", "/downloads/730 (1).lua", "730"},
+		{"name prefix only", "/downloads/1245620_backup.zip", "1245620"},
+		{"zip name wins over entries", named, "789"},
+		{"single lua in zip", single, "123"},
+		{"several lua in zip", multiple, ""},
+		{"no lua in zip", none, ""},
+		{"missing zip", filepath.Join(dir, "missing.zip"), ""},
+		{"lua without appid", "/downloads/notes.lua", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := localAppID(tt.path)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("localAppID(%q) = %q, %v; want ErrInvalidInput", tt.path, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("localAppID(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 日志级别
+const (
+	LevelDebug   = slog.LevelDebug // 调试信息(正则、匹配内容等)
+	LevelVerbose = slog.Level(-2)  // 详细过程(尝试的源、注释的行等)
+	LevelInfo    = slog.LevelInfo  // 常规信息
+	LevelWarn    = slog.LevelWarn  // 警告
+)
+
+// 日志级别名称
+var logLevels = map[string]slog.Level{
+	"quiet":   LevelWarn,
+	"normal":  LevelInfo,
+	"verbose": LevelVerbose,
+	"debug":   LevelDebug,
+}
+
+// 控制台输出(JSON 输出模式下为标准错误)
+var console io.Writer = os.Stdout
+
+// 当前控制台日志级别
+var consoleLevel = LevelInfo
+
+// 全局日志
+var logger = slog.New(newConsoleHandler(console, LevelInfo))
+
+// 已打开的日志文件
+var logFile *os.File
+
+// 初始化日志, file 为空时不输出到文件
+func SetupLogger(level, file string) error {
+	lv, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return Err("log.bad_level", level)
+	}
+	consoleLevel = lv
+
+	handlers := []slog.Handler{newConsoleHandler(console, lv)}
+
+	// 日志文件记录全部调试信息
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			return Err("log.open_failed", err)
+		}
+		logFile = f
+		handlers = append(handlers, slog.NewTextHandler(f, &slog.HandlerOptions{Level: LevelDebug}))
+	}
+
+	logger = slog.New(&multiHandler{handlers: handlers})
+	return nil
+}
+
+// 输出详细过程日志
+func logVerbose(msg string, args ...any) {
+	logger.Log(context.Background(), LevelVerbose, msg, args...)
+}
+
+// 输出分割线(静默模式下不输出)
+func printDivision() {
+	if consoleLevel <= LevelInfo {
+		fmt.Fprintln(console, Division)
+	}
+}
+
+// 输出下载进度(静默模式下不输出)
+func printProgress(format string, args ...any) {
+	if consoleLevel <= LevelInfo {
+		fmt.Fprintf(console, format, args...)
+	}
+}
+
+// 控制台日志处理器, 只输出消息, 调试模式下附带字段
+type consoleHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func newConsoleHandler(w io.Writer, level slog.Level) *consoleHandler {
+	return &consoleHandler{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString(T("log.prefix_error"))
+	case r.Level >= slog.LevelWarn:
+		b.WriteString(T("log.prefix_warn"))
+	case r.Level <= LevelDebug:
+		b.WriteString(T("log.prefix_debug"))
+	}
+	b.WriteString(r.Message)
+
+	// 调试模式下输出结构化字段
+	if h.level <= LevelDebug {
+		write := func(a slog.Attr) bool {
+			fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+			return true
+		}
+		for _, a := range h.attrs {
+			write(a)
+		}
+		r.Attrs(write)
+	}
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// 同时输出到多个处理器
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+func (m *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func (m *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
@@ -1,96 +1,367 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 )
 
-// 下载函数
-func Download(APPID string, downloadPath string) error {
-	// 尝试从多个源下载
-	data, err := TrySources(APPID)
+// 下载函数, ctx 被取消时中止网络请求和文件写入; 离线模式下从缓存读取
+func Download(ctx context.Context, APPID string, config *Config) (*DownloadResult, error) {
+	return runPipeline(ctx, APPID, config, func(ctx context.Context) (*FetchResult, error) {
+		if config.Offline {
+			return LoadCached(ctx, OpenCache(config), APPID)
+		}
+		return TrySources(ctx, APPID, OpenCache(config))
+	})
+}
+
+// 处理本地的 ZIP 或 Lua 文件, 步骤与下载相同, 但不访问清单镜像
+func DownloadFile(ctx context.Context, path string, config *Config) (*DownloadResult, error) {
+	APPID, err := localAppID(path)
 	if err != nil {
-		return err
+		return &DownloadResult{AppID: path, DLCsAdded: []string{}, Timings: map[string]int64{}, Error: err.Error()}, err
 	}
+	logger.Info(T("local.appid", APPID, path), "appid", APPID, "source", path)
+	return runPipeline(ctx, APPID, config, func(ctx context.Context) (*FetchResult, error) {
+		return LoadLocalFile(ctx, path, APPID)
+	})
+}
 
-	// 处理文件
-	modifiedData := ProcessFile(data)
+// 获取清单后依次处理文件、修补 DepotKey、保存并添加DLC
+func runPipeline(ctx context.Context, APPID string, config *Config, fetchManifest func(context.Context) (*FetchResult, error)) (*DownloadResult, error) {
+	result := &DownloadResult{
+		AppID:     APPID,
+		DLCsAdded: []string{},
+		Timings:   make(map[string]int64),
+	}
+	start := time.Now()
+	step := time.Now()
+	defer func() {
+		result.Timings["total"] = time.Since(start).Milliseconds()
+	}()
+
+	// 记录步骤耗时
+	mark := func(name string) {
+		result.Timings[name] = time.Since(step).Milliseconds()
+		step = time.Now()
+	}
 
-	// 下载 DepotKeys
-	depotkeys, err := DownloadDepotkeys()
+	// 获取清单
+	fetched, err := fetchManifest(ctx)
+	mark("fetch")
 	if err != nil {
-		fmt.Printf("下载 DepotKeys 失败: %v\n", err)
-	} else {
-		// 修补 DepotKey
-		modifiedData = PatchDepotkey(APPID, modifiedData, depotkeys)
+		var sourcesErr *SourcesError
+		if errors.As(err, &sourcesErr) {
+			result.Attempts = sourcesErr.Attempts
+		}
+		logSourcesReport(err, "appid", APPID, "step", "fetch")
+		result.Error = err.Error()
+		return result, err
+	}
+	result.Source = fetched.Source
+	result.Attempts = fetched.Attempts
+	if fetched.TempArchive {
+		defer os.Remove(fetched.Archive)
 	}
 
-	// 保存文件
+	// 处理文件
+	modifiedData, commented := ProcessFile(fetched.Data)
+	result.CommentedCount = commented
+	mark("process")
+
 	filename := APPID + ".lua"
-	fullPath := filepath.Join(downloadPath, filename)
+	fullPath := filepath.Join(config.DownloadPath, filename)
+
+	// 离线模式下不访问网络, 跳过 DepotKey 与DLC的查询
+	if config.Offline {
+		logger.Warn(T("offline.skipped"), "appid", APPID, "step", "offline")
+		result.Warnings = append(result.Warnings, T("offline.skipped"))
+		if config.RequireKey {
+			err = markError(ErrNoDepotKey, Err("offline.require_key"))
+			result.Error = err.Error()
+			return result, err
+		}
+		// 缓存的是未修补的原始清单, 不覆盖已下载的文件(其中可能有 DepotKey 与DLC)
+		if _, statErr := os.Stat(fullPath); statErr == nil && !config.Force {
+			err = markError(ErrNoDepotKey, Err("offline.would_overwrite", fullPath))
+			result.Error = err.Error()
+			return result, err
+		}
+	} else {
+		// 下载 DepotKeys
+		depotkeys, err := GetDepotkeys(ctx)
+		if err == nil {
+			_, err = LookupDepotkey(APPID, depotkeys)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// 已取消时不再继续
+			mark("depotkeys")
+			err = Err("cancel.canceled", ctxErr)
+			result.Error = err.Error()
+			return result, err
+		}
+		if err != nil {
+			logger.Warn(T("depotkeys.download_failed", err), "appid", APPID, "step", "depotkeys")
+			logSourcesReport(err, "appid", APPID, "step", "depotkeys")
+			result.Warnings = append(result.Warnings, err.Error())
+
+			// 要求必须有 DepotKey 时视为失败
+			if config.RequireKey {
+				mark("depotkeys")
+				err = markError(ErrNoDepotKey, err)
+				result.Error = err.Error()
+				return result, err
+			}
+		}
+		if depotkeys != nil {
+			// 修补 DepotKey
+			modifiedData, result.DepotKeysPatched = PatchDepotkey(APPID, modifiedData, depotkeys)
+		}
+		mark("depotkeys")
+	}
 
+	// 保存文件
 	// 使用配置的下载路径保存
-	if err := SaveFile(downloadPath, filename, modifiedData); err != nil {
-		return err
+	if err := SaveFile(ctx, config.DownloadPath, filename, modifiedData); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.OutputPath = fullPath
+	result.Bytes = len(modifiedData)
+	mark("save")
+
+	// 解压 ZIP 中的其余文件(如 .manifest 与DLC的 Lua 文件)
+	if config.ExtractAll && fetched.Archive != "" {
+		extractDir := filepath.Join(config.DownloadPath, APPID)
+		logger.Info(T("zip.extracting", extractDir), "appid", APPID, "step", "extract")
+		files, err := ExtractArchive(ctx, fetched.Archive, extractDir, APPID)
+		result.ExtractedFiles = files
+		if len(files) > 0 {
+			result.ExtractDir = extractDir
+		}
+		mark("extract")
+		if err != nil {
+			if ctx.Err() != nil {
+				result.Error = err.Error()
+				return result, err
+			}
+			logger.Warn(T("zip.extract_failed", err), "appid", APPID, "step", "extract")
+			result.Warnings = append(result.Warnings, err.Error())
+		} else {
+			logger.Info(T("zip.extract_done", len(files), extractDir), "appid", APPID, "step", "extract")
+		}
 	}
 
 	// 下载完成后添加DLC
-	fmt.Println(Division)
-	fmt.Println("开始添加无仓库的DLC...")
-	if err := AddDLC(APPID, fullPath); err != nil {
-		fmt.Printf("添加DLC失败: %v\n", err)
-	} else {
-		fmt.Println("DLC添加完成")
+	if !config.Offline {
+		printDivision()
+		logger.Info(T("dlc.start"), "appid", APPID, "step", "dlc")
+		added, detail, err := AddDLC(ctx, APPID, fullPath, config.DLCFilter)
+		mark("dlc")
+		if detail != nil {
+			result.Name = detail.Name
+			result.DLCProvider = detail.Provider
+		}
+		if err != nil && ctx.Err() != nil {
+			// 清单已保存, 取消只影响DLC的添加
+			err = Err("cancel.canceled", ctx.Err())
+			result.Error = err.Error()
+			return result, err
+		}
+		if err != nil {
+			logger.Warn(T("dlc.failed", err), "appid", APPID, "step", "dlc")
+			result.Warnings = append(result.Warnings, err.Error())
+		} else {
+			logger.Info(T("dlc.done"), "appid", APPID, "step", "dlc")
+			result.DLCsAdded = added
+			if info, err := os.Stat(fullPath); err == nil {
+				result.Bytes = int(info.Size())
+			}
+		}
+	}
+
+	// 记录到下载库
+	if err := RecordDownload(config.DownloadPath, result); err != nil {
+		logger.Warn(T("library.record_failed", err), "appid", APPID, "step", "library")
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+	return result, nil
+}
+
+// 交互模式下单次操作(搜索或下载)收到 SIGTERM 时返回的错误, 调用方应结束程序
+var errTerminated = errors.New("terminated")
+
+// 交互模式下单次操作(搜索或下载)的上下文: 收到 Ctrl+C 时只取消本次操作, 收到 SIGTERM 时取消后结束程序
+// 返回的 stop 停止监听信号, 并报告是否收到了 SIGTERM
+func interactiveContext() (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	var terminated bool
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case sig := <-signals:
+			terminated = sig == syscall.SIGTERM
+			cancel()
+		case <-done:
+		}
+	}()
+
+	return ctx, func() bool {
+		signal.Stop(signals)
+		close(done)
+		<-exited
+		cancel()
+		return terminated
 	}
-	return nil
 }
 
 // 主程序
 func main() {
+	// 根据环境变量与系统区域设置选择语言, 加载配置后再按配置调整
+	SetLanguage(DetectLanguage(""))
+
+	// 解析命令行参数
+	output := flag.String("output", OutputText, T("flag.output"))
+	logLevel := flag.String("log-level", "", T("flag.log_level"))
+	logFilePath := flag.String("log-file", "", T("flag.log_file"))
+	requireKey := flag.Bool("require-key", false, T("flag.require_key"))
+	extractAll := flag.Bool("extract-all", false, T("flag.extract_all"))
+	offline := flag.Bool("offline", false, T("flag.offline"))
+	force := flag.Bool("force", false, T("flag.force"))
+	flag.Parse()
+
+	// JSON 输出模式下, 标准输出只保留结果记录, 其余信息转到标准错误
+	if *output != OutputText {
+		console = os.Stderr
+	}
+	if err := SetupLogger(orDefault(*logLevel, "normal"), *logFilePath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	// 输出
-	fmt.Println(Division)
-	fmt.Println("ManifestHub CLI - 新一代密钥获取工具")
-	fmt.Println(Division)
-	fmt.Println("开发者:LANREN")
-	fmt.Println("版本号:V1.2")
+	printDivision()
+	logger.Info(T("app.title"))
+	printDivision()
+	logger.Info(T("app.developer"))
+	logger.Info(T("app.version"))
 
 	// 加载配置
 	config := LoadConfig()
+	config.Output = *output
+	config.RequireKey = *requireKey
+	config.ExtractAll = config.ExtractAll || *extractAll
+	config.Offline = *offline
+	config.Force = *force
+
+	// 按配置选择语言
+	if err := SetLanguage(DetectLanguage(config.Language)); err != nil {
+		logger.Warn(T("config.ignored", err))
+	}
+	if missing := CheckCatalogs(); len(missing) > 0 {
+		logger.Debug(T("i18n.missing_count", len(missing)))
+	}
+
+	// 命令行参数优先于配置文件
+	if err := SetupLogger(orDefault(*logLevel, config.LogLevel), orDefault(*logFilePath, config.LogFile)); err != nil {
+		logger.Warn(T("log.setup_failed", err))
+	}
+	defer func() {
+		if logFile != nil {
+			logFile.Close()
+		}
+	}()
 
+	// 命令行模式, 收到 SIGINT/SIGTERM 时取消正在进行的下载
+	if flag.NArg() > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := RunCommand(ctx, config, flag.Args(), os.Stdout)
+		stop()
+		if err != nil {
+			logger.Error(T("cmd.failed", err))
+			if logFile != nil {
+				logFile.Close()
+			}
+			os.Exit(ExitCode(err))
+		}
+		return
+	}
+
+	// 交互模式, 等待输入时信号按默认方式处理(直接退出)
 	for {
 		// 输出输入
-		OriginUserAPPID, err := GetAppID()
+		OriginUserAPPID, localPath, err := GetAppID(config)
 		if err != nil {
+			switch {
 			// 如果是 EOF（比如输入流关闭或用户退出），优雅退出程序
-			if err == io.EOF {
-				fmt.Println("\n输入已关闭，程序退出")
+			case errors.Is(err, io.EOF):
+				fmt.Fprintln(console)
+				logger.Info(T("app.input_closed"))
+				return
+			case errors.Is(err, errTerminated):
+				logger.Warn(T("cancel.terminated"))
 				return
+			case errors.Is(err, context.Canceled):
+				printProgress("\n")
+				logger.Warn(T("cancel.search_canceled"))
+			default:
+				logger.Error(T("app.get_appid_failed", err))
 			}
-			fmt.Printf("获取AppID失败: %v\n", err)
 			continue
 		}
 
 		// 显示下载信息
 		UserAPPID := strconv.Itoa(OriginUserAPPID)
-		fmt.Println(Division)
-		fmt.Printf("开始下载: %s.lua\n", UserAPPID)
-		fmt.Println("尝试以下下载源:")
-		for i, source := range Sources {
-			fmt.Printf(" %d. %s\n", i+1, fmt.Sprintf(source, UserAPPID, UserAPPID))
+		printDivision()
+		if localPath != "" {
+			// 本地文件不显示下载源
+			UserAPPID = localPath
+			logger.Info(T("local.start", localPath), "source", localPath)
+		} else {
+			logger.Info(T("download.start", UserAPPID), "appid", UserAPPID)
+			logVerbose(T("download.sources"), "appid", UserAPPID)
+			for i, source := range Sources {
+				logVerbose(fmt.Sprintf(" %d. %s", i+1, expandTemplate(source.Name(), UserAPPID)), "appid", UserAPPID)
+			}
 		}
-		fmt.Printf(" 5. %s\n", fmt.Sprintf(zipSource, UserAPPID))
-		fmt.Println(Division)
+		printDivision()
 
-		// 调用下载函数
+		// 调用下载函数, 下载过程中 Ctrl+C 只取消本次下载并回到输入提示
 		startTime := time.Now()
-		if err := Download(UserAPPID, config.DownloadPath); err != nil {
-			fmt.Printf("下载失败: %v\n", err)
+		ctx, stop := interactiveContext()
+		if localPath != "" {
+			_, err = DownloadFile(ctx, localPath, config)
+		} else {
+			_, err = Download(ctx, UserAPPID, config)
+		}
+		canceled := ctx.Err() != nil
+		terminated := stop()
+		switch {
+		case terminated:
+			logger.Warn(T("cancel.terminated"), "appid", UserAPPID)
+			return
+		case canceled:
+			printProgress("\n")
+			logger.Warn(T("cancel.download_canceled", UserAPPID), "appid", UserAPPID)
+		case err != nil:
+			logger.Error(T("download.failed", err), "appid", UserAPPID)
 		}
 
-		fmt.Println(Division)
-		fmt.Printf("耗时: %.2f秒\n", time.Since(startTime).Seconds())
+		printDivision()
+		logger.Info(T("download.elapsed", time.Since(startTime).Seconds()), "appid", UserAPPID)
 	}
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 离线模式下缓存中是未修补的原始清单, 不应覆盖已下载的文件
+func TestRunPipelineOffline(t *testing.T) {
+	quietLogs(t)
+	existing := "addappid(10,1,\"KEY\")\naddappid(12)\n"
+	cached := func(context.Context) (*FetchResult, error) {
+		return &FetchResult{Data: []byte("addappid(10)\n"), Source: "cache"}, nil
+	}
+
+	tests := []struct {
+		name       string
+		existing   bool
+		force      bool
+		requireKey bool
+		wantErr    error
+		wantFile   string // 结束后文件中应有的内容
+	}{
+		{"new file", false, false, false, nil, "addappid(10)"},
+		{"existing file kept", true, false, false, ErrNoDepotKey, `addappid(10,1,"KEY")`},
+		{"existing file with force", true, true, false, nil, "addappid(10)"},
+		{"require key", true, true, true, ErrNoDepotKey, `addappid(10,1,"KEY")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{DownloadPath: t.TempDir(), Offline: true, Force: tt.force, RequireKey: tt.requireKey}
+			path := filepath.Join(config.DownloadPath, "10.lua")
+			if tt.existing {
+				if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := runPipeline(context.Background(), "10", config, cached)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("runPipeline error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && (ExitCode(err) != ExitNoDepotKey || result.Error == "") {
+				t.Errorf("exit code = %d, result error = %q; want %d with error", ExitCode(err), result.Error, ExitNoDepotKey)
+			}
+			data, readErr := os.ReadFile(path)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			if !strings.Contains(string(data), tt.wantFile) {
+				t.Errorf("file = %q, want it to contain %q", data, tt.wantFile)
+			}
+			// 失败时不记录到下载库
+			if _, statErr := os.Stat(filepath.Join(config.DownloadPath, libraryFile)); (statErr == nil) != (err == nil) {
+				t.Errorf("%s exists = %v, want %v", libraryFile, statErr == nil, err == nil)
+			}
+		})
+	}
+}
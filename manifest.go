@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Lua 清单中的条目
+var (
+	addAppIDRegex      = regexp.MustCompile(`^addappid\s*\(\s*(\d+)(?:\s*,\s*\d+\s*,\s*"([^"]*)")?`)
+	setManifestIDRegex = regexp.MustCompile(`^setManifestid\s*\(\s*(\d+)\s*,\s*"?(\d+)"?`)
+)
+
+// 解析后的清单
+type ManifestEntries struct {
+	Apps      map[string]string // addappid 的 AppID/DepotID -> DepotKey, 没有密钥时为空
+	Manifests map[string]string // setManifestid 的 DepotID -> ManifestID
+}
+
+// 解析 Lua 清单; 处理时被注释的 setManifestid 行同样计入
+func ParseManifest(data []byte) *ManifestEntries {
+	entries := &ManifestEntries{Apps: map[string]string{}, Manifests: map[string]string{}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		if m := addAppIDRegex.FindStringSubmatch(line); m != nil {
+			entries.Apps[m[1]] = m[2]
+		} else if m := setManifestIDRegex.FindStringSubmatch(line); m != nil {
+			entries.Manifests[m[1]] = m[2]
+		}
+	}
+	return entries
+}
+
+// 两个版本中 ManifestID 不同(包括新增和删除)的 Depot 数
+func changedManifests(old, new *ManifestEntries) int {
+	changed := 0
+	for depot, id := range new.Manifests {
+		if old.Manifests[depot] != id {
+			changed++
+		}
+	}
+	for depot := range old.Manifests {
+		if _, ok := new.Manifests[depot]; !ok {
+			changed++
+		}
+	}
+	return changed
+}
+
+// 是否为 Depot(有 DepotKey 或 ManifestID); 其余的 addappid 为游戏本身或DLC
+func (m *ManifestEntries) isDepot(id string) bool {
+	_, hasManifest := m.Manifests[id]
+	return hasManifest || m.Apps[id] != ""
+}
+
+// 清单中是否有该 ID 的条目
+func (m *ManifestEntries) has(id string) bool {
+	_, inApps := m.Apps[id]
+	_, inManifests := m.Manifests[id]
+	return inApps || inManifests
+}
+
+// 清单中的所有 Depot
+func (m *ManifestEntries) depots() map[string]bool {
+	depots := make(map[string]bool)
+	for id := range m.Apps {
+		if m.isDepot(id) {
+			depots[id] = true
+		}
+	}
+	for id := range m.Manifests {
+		depots[id] = true
+	}
+	return depots
+}
+
+// 两个版本之间变化的值
+type ValueChange struct {
+	ID  string `json:"id"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// 两个版本的清单在条目上的差异, 各列表按 ID 排序
+type ManifestDiff struct {
+	DepotsAdded      []string      `json:"depots_added"`
+	DepotsRemoved    []string      `json:"depots_removed"`
+	ManifestsChanged []ValueChange `json:"manifests_changed"` // Depot 的 ManifestID 变化
+	KeysChanged      []ValueChange `json:"keys_changed"`      // DepotKey 变化(包括新增和删除密钥)
+	DLCsAdded        []string      `json:"dlcs_added"`
+	DLCsRemoved      []string      `json:"dlcs_removed"`
+}
+
+// 是否没有任何差异
+func (d *ManifestDiff) Empty() bool {
+	return len(d.DepotsAdded)+len(d.DepotsRemoved)+len(d.ManifestsChanged)+
+		len(d.KeysChanged)+len(d.DLCsAdded)+len(d.DLCsRemoved) == 0
+}
+
+// 比较两个版本的清单
+func DiffManifests(old, new *ManifestEntries) *ManifestDiff {
+	diff := &ManifestDiff{
+		DepotsAdded:      []string{},
+		DepotsRemoved:    []string{},
+		ManifestsChanged: []ValueChange{},
+		KeysChanged:      []ValueChange{},
+		DLCsAdded:        []string{},
+		DLCsRemoved:      []string{},
+	}
+
+	// Depot 的增删及 ManifestID 变化
+	// 只增加了密钥的条目(如修补了 DepotKey 的游戏本身)只计入密钥变化
+	oldDepots, newDepots := old.depots(), new.depots()
+	for id := range newDepots {
+		switch {
+		case !old.has(id):
+			diff.DepotsAdded = append(diff.DepotsAdded, id)
+		case old.Manifests[id] != new.Manifests[id]:
+			diff.ManifestsChanged = append(diff.ManifestsChanged, ValueChange{ID: id, Old: old.Manifests[id], New: new.Manifests[id]})
+		}
+	}
+	for id := range oldDepots {
+		if !new.has(id) {
+			diff.DepotsRemoved = append(diff.DepotsRemoved, id)
+		}
+	}
+
+	// 两个版本中都有的条目比较密钥, 其余的 addappid 视为DLC的增删
+	for id, key := range new.Apps {
+		oldKey, ok := old.Apps[id]
+		switch {
+		case ok && oldKey != key:
+			diff.KeysChanged = append(diff.KeysChanged, ValueChange{ID: id, Old: oldKey, New: key})
+		case !ok && !new.isDepot(id):
+			diff.DLCsAdded = append(diff.DLCsAdded, id)
+		}
+	}
+	for id := range old.Apps {
+		if _, ok := new.Apps[id]; !ok && !old.isDepot(id) {
+			diff.DLCsRemoved = append(diff.DLCsRemoved, id)
+		}
+	}
+
+	for _, ids := range [][]string{diff.DepotsAdded, diff.DepotsRemoved, diff.DLCsAdded, diff.DLCsRemoved} {
+		sort.Slice(ids, func(i, j int) bool { return appIDLess(ids[i], ids[j]) })
+	}
+	for _, changes := range [][]ValueChange{diff.ManifestsChanged, diff.KeysChanged} {
+		sort.Slice(changes, func(i, j int) bool { return appIDLess(changes[i].ID, changes[j].ID) })
+	}
+	return diff
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		wantApps      map[string]string
+		wantManifests map[string]string
+	}{
+		{"empty", "", map[string]string{}, map[string]string{}},
+		{
+			"apps, keys and manifests",
+			"addappid(10)\naddappid(11, 1, \"aa\")\nsetManifestid(11, \"100\")\nsetManifestid(12,200)\n",
+			map[string]string{"10": "", "11": "aa"},
+			map[string]string{"11": "100", "12": "200"},
+		},
+		{
+			"commented lines count",
+			"-- addappid(20)\n  --setManifestid(21,\"300\")\n",
+			map[string]string{"20": ""},
+			map[string]string{"21": "300"},
+		},
+		{
+			"unrelated lines ignored",
+			"-- generated\nlocal x = 1\naddtoken(10, \"t\")\naddappid(abc)\n",
+			map[string]string{},
+			map[string]string{},
+		},
+		{
+			"windows line endings and indentation",
+			"  addappid( 30 , 0 , \"cc\" )\r\n\tsetManifestid( 30 , \"400\" )\r\n",
+			map[string]string{"30": "cc"},
+			map[string]string{"30": "400"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseManifest([]byte(tt.data))
+			if !maps.Equal(got.Apps, tt.wantApps) || !maps.Equal(got.Manifests, tt.wantManifests) {
+				t.Errorf("ParseManifest = %v, %v; want %v, %v", got.Apps, got.Manifests, tt.wantApps, tt.wantManifests)
+			}
+		})
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	base := "addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\naddappid(12,1,\"bb\")\nsetManifestid(12,\"200\")\naddappid(20)\n"
+
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want ManifestDiff
+	}{
+		{"identical", base, base, ManifestDiff{}},
+		{
+			"manifest changed",
+			base,
+			"addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"101\")\naddappid(12,1,\"bb\")\nsetManifestid(12,\"200\")\naddappid(20)\n",
+			ManifestDiff{ManifestsChanged: []ValueChange{{ID: "11", Old: "100", New: "101"}}},
+		},
+		{
+			"depots added and removed",
+			base,
+			"addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\naddappid(9,1,\"cc\")\nsetManifestid(9,\"300\")\naddappid(13,1,\"dd\")\naddappid(20)\n",
+			ManifestDiff{DepotsAdded: []string{"9", "13"}, DepotsRemoved: []string{"12"}},
+		},
+		{
+			"key changed",
+			base,
+			"addappid(10)\naddappid(11,1,\"zz\")\nsetManifestid(11,\"100\")\naddappid(12,1,\"bb\")\nsetManifestid(12,\"200\")\naddappid(20)\n",
+			ManifestDiff{KeysChanged: []ValueChange{{ID: "11", Old: "aa", New: "zz"}}},
+		},
+		{
+			// 给游戏本身补上密钥不算新增 Depot
+			"key added to existing app",
+			base,
+			"addappid(10,1,\"kk\")\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\naddappid(12,1,\"bb\")\nsetManifestid(12,\"200\")\naddappid(20)\n",
+			ManifestDiff{KeysChanged: []ValueChange{{ID: "10", Old: "", New: "kk"}}},
+		},
+		{
+			"dlcs added and removed",
+			base,
+			"addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\naddappid(12,1,\"bb\")\nsetManifestid(12,\"200\")\naddappid(100)\naddappid(21)\n",
+			ManifestDiff{DLCsAdded: []string{"21", "100"}, DLCsRemoved: []string{"20"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffManifests(ParseManifest([]byte(tt.old)), ParseManifest([]byte(tt.new)))
+			if !reflect.DeepEqual(*got, emptyDiff(tt.want)) {
+				t.Errorf("DiffManifests = %+v, want %+v", *got, tt.want)
+			}
+			if got.Empty() != tt.want.Empty() {
+				t.Errorf("Empty() = %v", got.Empty())
+			}
+		})
+	}
+}
+
+// 把 nil 列表换成空列表, 与 DiffManifests 的结果一致
+func emptyDiff(d ManifestDiff) ManifestDiff {
+	for _, ids := range []*[]string{&d.DepotsAdded, &d.DepotsRemoved, &d.DLCsAdded, &d.DLCsRemoved} {
+		if *ids == nil {
+			*ids = []string{}
+		}
+	}
+	for _, changes := range []*[]ValueChange{&d.ManifestsChanged, &d.KeysChanged} {
+		if *changes == nil {
+			*changes = []ValueChange{}
+		}
+	}
+	return d
+}
+
+func TestChangedManifests(t *testing.T) {
+	old := ParseManifest([]byte("setManifestid(1,\"10\")\nsetManifestid(2,\"20\")\nsetManifestid(3,\"30\")\n"))
+	new := ParseManifest([]byte("setManifestid(1,\"10\")\nsetManifestid(2,\"21\")\nsetManifestid(4,\"40\")\n"))
+	// 2 变化, 3 删除, 4 新增
+	if got := changedManifests(old, new); got != 3 {
+		t.Fatalf("changedManifests = %d, want 3", got)
+	}
+	if got := changedManifests(old, old); got != 0 {
+		t.Fatalf("changedManifests of same manifest = %d, want 0", got)
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	before, after := filepath.Join(dir, "before.lua"), filepath.Join(dir, "after.lua")
+	if err := os.WriteFile(before, []byte("addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(after, []byte("addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"101\")\naddappid(20)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := RunDiff(&Config{Output: OutputNDJSON}, []string{before, after}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if n := ndjsonLines(t, out.String()); n != 1 {
+		t.Fatalf("RunDiff wrote %d lines, want 1:\n%s", n, out.String())
+	}
+
+	if err := RunDiff(&Config{}, []string{"10"}, &out); ExitCode(err) != ExitInvalidInput {
+		t.Errorf("RunDiff without cache: exit code %d, want %d", ExitCode(err), ExitInvalidInput)
+	}
+	if err := RunDiff(&Config{}, nil, &out); ExitCode(err) != ExitInvalidInput {
+		t.Errorf("RunDiff without arguments: exit code %d, want %d", ExitCode(err), ExitInvalidInput)
+	}
+}
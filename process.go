@@ -2,19 +2,20 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
-	"strconv"
 	"strings"
 )
 
-// 保存文件到配置路径
-func SaveFile(path, filename string, data []byte) error {
+// 保存文件到配置路径, 先写入临时文件再重命名, 中断时不会留下写了一半的文件
+func SaveFile(ctx context.Context, path, filename string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return Err("cancel.canceled", err)
+	}
+
 	// 确保路径是绝对路径
 	absPath, err := filepath.Abs(path)
 	if err == nil {
@@ -23,24 +24,35 @@ func SaveFile(path, filename string, data []byte) error {
 
 	// 确保目录存在
 	if err := os.MkdirAll(path, os.ModePerm); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
+		return Err("fs.mkdir_failed", err)
 	}
 
 	// 创建完整文件路径
 	fullPath := filepath.Join(path, filename)
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
-		return fmt.Errorf("保存文件失败: %v", err)
+	tmpPath := fullPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		os.Remove(tmpPath)
+		return Err("fs.save_failed", err)
+	}
+	if err := ctx.Err(); err != nil {
+		os.Remove(tmpPath)
+		return Err("cancel.canceled", err)
+	}
+	if err := os.Rename(tmpPath, fullPath); err != nil {
+		os.Remove(tmpPath)
+		return Err("fs.save_failed", err)
 	}
 
-	fmt.Printf("文件已保存到: %s (%d字节)\n", fullPath, len(data))
+	logger.Info(T("fs.saved", fullPath, len(data)), "step", "save")
 	return nil
 }
 
-// 文件处理
-func ProcessFile(data []byte) []byte {
+// 文件处理, 返回处理后的数据及注释的 setManifest 行数
+func ProcessFile(data []byte) ([]byte, int) {
 	// 定义变量
 	scanner := bufio.NewScanner(strings.NewReader(string(data)))
 	var builder strings.Builder
+	commented := 0
 
 	// 死循环
 	for scanner.Scan() {
@@ -51,7 +63,8 @@ func ProcessFile(data []byte) []byte {
 		// 注释包含 setManifest 且未被注释的行
 		if strings.Contains(trimmedLine, "setManifest") && !strings.HasPrefix(trimmedLine, "--") {
 			builder.WriteString("-- ")
-			fmt.Printf("已注释: %s\n", strings.TrimSpace(line))
+			commented++
+			logVerbose(T("process.commented_line", strings.TrimSpace(line)), "step", "process")
 		}
 
 		builder.WriteString(line)
@@ -59,70 +72,109 @@ func ProcessFile(data []byte) []byte {
 	}
 
 	// 返回
-	fmt.Println(Division)
-	return []byte(builder.String())
+	logger.Info(T("process.commented", commented), "step", "process")
+	printDivision()
+	return []byte(builder.String()), commented
 }
 
-// 修补 DepotKey
-func PatchDepotkey(APPID string, data []byte, depotkeys map[string]string) []byte {
+// 查找 DepotKey
+func LookupDepotkey(APPID string, depotkeys map[string]string) (string, error) {
 	depotkey, exists := depotkeys[APPID]
 	if !exists {
-		fmt.Printf("没有找到AppID %s 的 DepotKey\n", APPID)
-		return data
+		return "", markError(ErrNoDepotKey, Err("depotkeys.no_key", APPID))
 	}
+	return depotkey, nil
+}
 
-	fmt.Printf("找到 AppID %s 的 DepotKey: %s\n", APPID, depotkey)
+// 修补 DepotKey, 返回修补后的数据及修补的数量
+func PatchDepotkey(APPID string, data []byte, depotkeys map[string]string) ([]byte, int) {
+	depotkey, err := LookupDepotkey(APPID, depotkeys)
+	if err != nil {
+		logger.Info(err.Error(), "appid", APPID, "step", "depotkeys")
+		return data, 0
+	}
+
+	logger.Info(T("depotkeys.found", APPID, depotkey), "appid", APPID, "step", "depotkeys")
 
 	// 创建正则表达式
 	patternStr := `addappid\s*\(\s*` + regexp.QuoteMeta(APPID) + `\s*\)`
-	fmt.Printf("使用的正则表达式: %s\n", patternStr)
+	logger.Debug(T("depotkeys.regex", patternStr), "appid", APPID, "step", "depotkeys")
 	pattern := regexp.MustCompile(patternStr)
 
 	// 检查匹配
-	if matches := pattern.Find(data); matches != nil {
-		fmt.Printf("发现匹配内容: %s\n", string(matches))
-		fmt.Printf("发现需要修补的 addappid(%s)\n", APPID)
+	if matches := pattern.FindAll(data, -1); matches != nil {
+		logger.Debug(T("depotkeys.match", string(matches[0])), "appid", APPID, "step", "depotkeys")
+		logVerbose(T("depotkeys.patch_target", APPID), "appid", APPID, "step", "depotkeys")
 
 		// 替换为带 DepotKey 的版本
 		replacement := fmt.Sprintf("addappid(%s,1,\"%s\")", APPID, depotkey)
-		fmt.Printf("替换为: %s\n", replacement)
+		logger.Debug(T("depotkeys.replacement", replacement), "appid", APPID, "step", "depotkeys")
 
 		patched := pattern.ReplaceAll(data, []byte(replacement))
 
-		fmt.Println("已修补 DepotKey")
-		fmt.Println(Division)
-		return patched
+		logger.Info(T("depotkeys.patched"), "appid", APPID, "step", "depotkeys")
+		printDivision()
+		return patched, len(matches)
 	}
 
-	fmt.Printf("未找到需要修补的 addappid(%s)\n", APPID)
-	fmt.Println(Division)
-	return data
+	logger.Info(T("depotkeys.no_target", APPID), "appid", APPID, "step", "depotkeys")
+	printDivision()
+	return data, 0
 }
 
-// 添加 DLC 到 Lua 文件
-func AddDLC(appid, luaFilePath string) error {
+// 添加 DLC 到 Lua 文件, 返回添加的DLC AppID 及主游戏的信息(获取失败时为 nil)
+func AddDLC(ctx context.Context, appid, luaFilePath string, filter *DLCFilter) ([]string, *AppDetail, error) {
 	// 获取游戏的基本信息
-	mainDLCs, _, err := GetDLCInfo(appid)
+	mainDetail, err := GetAppDetail(ctx, appid)
 	if err != nil {
-		return fmt.Errorf("获取主游戏DLC失败: %v", err)
+		return nil, nil, Err("dlc.main_failed", err)
 	}
+	logger.Info(T("dlc.provider", mainDetail.Provider), "appid", appid, "source", mainDetail.Provider, "step", "dlc")
 
-	// 筛选无仓库的DLC
+	// 筛选无仓库的DLC, 并应用过滤规则
 	var dlcIDs []string
-	for _, dlcID := range mainDLCs {
-		_, hasDepots, err := GetDLCInfo(dlcID)
+	var decisions []string
+	for _, dlcID := range mainDetail.DLCs {
+		if err := ctx.Err(); err != nil {
+			return nil, mainDetail, Err("cancel.canceled", err)
+		}
+		detail, err := GetAppDetail(ctx, dlcID)
 		if err != nil {
-			fmt.Printf("获取DLC %s 信息失败: %v\n", dlcID, err)
+			logger.Warn(T("dlc.detail_failed", dlcID, err), "appid", appid, "step", "dlc")
 			continue
 		}
 
-		if !hasDepots {
+		// 仓库信息未知时无法判断是否为无仓库的DLC, 除非配置了 dlcUnknownDepots = add, 否则不添加
+		if !detail.DepotsKnown {
+			if !filter.addsUnknown() {
+				logger.Warn(T("dlc.depots_unknown", detail.Provider, dlcID), "appid", appid, "source", detail.Provider, "step", "dlc")
+				continue
+			}
+			logger.Warn(T("dlc.depots_unknown_added", detail.Provider, dlcID), "appid", appid, "source", detail.Provider, "step", "dlc")
+		} else if detail.HasDepots {
+			continue
+		}
+
+		keep, reason := filter.Decide(appid, detail)
+		status := T("dlc.keep")
+		if keep {
 			dlcIDs = append(dlcIDs, dlcID)
+		} else {
+			status = T("dlc.exclude")
+		}
+		decisions = append(decisions, T("dlc.decision", status, dlcID, detail.Name, reason, detail.Provider))
+	}
+
+	// 输出过滤结果
+	if len(decisions) > 0 {
+		logger.Info(T("dlc.filter_summary", len(dlcIDs), len(decisions)), "appid", appid, "step", "dlc")
+		for _, decision := range decisions {
+			logger.Info(decision, "appid", appid, "step", "dlc")
 		}
 	}
 
 	if len(dlcIDs) == 0 {
-		return fmt.Errorf("未找到无仓库的DLC")
+		return nil, mainDetail, Err("dlc.none")
 	}
 
 	// 读取现有LUA内容
@@ -130,7 +182,7 @@ func AddDLC(appid, luaFilePath string) error {
 	if _, err := os.Stat(luaFilePath); err == nil {
 		file, err := os.Open(luaFilePath)
 		if err != nil {
-			return fmt.Errorf("打开文件失败: %v", err)
+			return nil, mainDetail, Err("fs.open_failed", err)
 		}
 		defer file.Close()
 
@@ -152,126 +204,67 @@ func AddDLC(appid, luaFilePath string) error {
 	}
 
 	// 添加新DLC
-	var newLines []string
+	var newIDs []string
 	for _, dlcID := range dlcIDs {
 		if !existingAppids[dlcID] {
-			newLines = append(newLines, fmt.Sprintf("addappid(%s)", dlcID))
+			newIDs = append(newIDs, dlcID)
 		}
 	}
 
-	if len(newLines) == 0 {
-		return fmt.Errorf("所有无仓库的DLC已存在于解锁文件中")
+	if len(newIDs) == 0 {
+		return nil, mainDetail, Err("dlc.all_exist")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, mainDetail, Err("cancel.canceled", err)
 	}
 
 	// 保存回文件
 	file, err := os.OpenFile(luaFilePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
-		return fmt.Errorf("打开文件失败: %v", err)
+		return nil, mainDetail, Err("fs.open_failed", err)
 	}
 	defer file.Close()
 
-	for _, line := range newLines {
+	var added []string
+	for _, dlcID := range newIDs {
+		line := fmt.Sprintf("addappid(%s)", dlcID)
 		if _, err := file.WriteString(line + "\n"); err != nil {
-			fmt.Printf("写入DLC %s 失败: %v\n", line, err)
+			logger.Warn(T("dlc.write_failed", line, err), "appid", appid, "step", "dlc")
 		} else {
-			fmt.Printf("添加DLC: %s\n", line)
+			logger.Info(T("dlc.added", line), "appid", appid, "step", "dlc")
+			added = append(added, dlcID)
 		}
 	}
 
-	return nil
+	return added, mainDetail, nil
 }
 
 // 获取DLC信息
-func GetDLCInfo(appid string) ([]string, bool, error) {
-	url := fmt.Sprintf(DLCInfoURL, appid)
-	resp, err := httpClient.Get(url)
+func GetDLCInfo(ctx context.Context, appid string) ([]string, bool, error) {
+	detail, err := GetAppDetail(ctx, appid)
 	if err != nil {
-		return nil, false, fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, false, fmt.Errorf("HTTP状态码错误: %d", resp.StatusCode)
-	}
-
-	var info DLCInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return nil, false, fmt.Errorf("解析JSON失败: %v", err)
-	}
-
-	appData, ok := info.Data[appid]
-	if !ok {
-		return nil, false, fmt.Errorf("未找到AppID %s 的信息", appid)
-	}
-
-	// 提取所有可能的DLC ID来源
-	dlcIDs := make(map[string]bool)
-
-	// 从common.listofdlc中提取
-	if listStr, ok := appData.Common["listofdlc"].(string); ok {
-		re := regexp.MustCompile(`\d+`)
-		matches := re.FindAllString(listStr, -1)
-		for _, id := range matches {
-			dlcIDs[id] = true
-		}
+		return nil, false, err
 	}
+	return detail.DLCs, detail.HasDepots, nil
+}
 
-	// 从extended.listofdlc中提取
-	if listStr, ok := appData.Extended["listofdlc"].(string); ok {
-		re := regexp.MustCompile(`\d+`)
-		matches := re.FindAllString(listStr, -1)
-		for _, id := range matches {
-			dlcIDs[id] = true
+// 获取应用详情, 按顺序尝试各信息源
+func GetAppDetail(ctx context.Context, appid string) (*AppDetail, error) {
+	var lastError error
+	for _, provider := range AppInfoProviders {
+		if err := ctx.Err(); err != nil {
+			return nil, Err("cancel.canceled", err)
 		}
-	}
-
-	// 从depots.dlc列表中提取
-	if appData.Depots != nil {
-		if depotsMap, ok := appData.Depots.(map[string]interface{}); ok {
-			if dlcMap, ok := depotsMap["dlc"]; ok {
-				// dlcMap 可能是 map 也可能是其他类型
-				switch v := dlcMap.(type) {
-				case map[string]interface{}:
-					// 遍历这个map的键
-					for dlcID := range v {
-						dlcIDs[dlcID] = true
-					}
-				case string:
-					// 如果是字符串, 跳过或者记录日志
-					fmt.Printf("警告: DLC 字段是字符串: %s\n", v)
-				default:
-					fmt.Printf("警告: DLC 字段的类型异常: %T\n", v)
-				}
-			}
-		} else {
-			fmt.Printf("Warning: depots is not a map: %T\n", appData.Depots)
+		detail, err := provider.GetAppDetail(ctx, appid)
+		if err != nil {
+			lastError = fmt.Errorf("%s: %w", provider.Name(), err)
+			continue
 		}
+		detail.Provider = provider.Name()
+		return detail, nil
 	}
-
-	// 从dlc字典中提取
-	for id := range appData.DLC {
-		dlcIDs[id] = true
-	}
-
-	// 转换为切片并排序
-	dlcIDsSlice := make([]string, 0, len(dlcIDs))
-	for id := range dlcIDs {
-		dlcIDsSlice = append(dlcIDsSlice, id)
+	if lastError == nil {
+		lastError = Err("provider.none")
 	}
-	sort.Slice(dlcIDsSlice, func(i, j int) bool {
-		a, _ := strconv.Atoi(dlcIDsSlice[i])
-		b, _ := strconv.Atoi(dlcIDsSlice[j])
-		return a < b
-	})
-
-	// 检查是否有仓库
-	hasDepots := false
-	if depots, ok := appData.Depots.(map[string]interface{}); ok && len(depots) > 0 {
-		hasDepots = true
-	} else if _, ok := appData.Depots.(string); ok {
-		// 字符串类型的depots也算有仓库
-		hasDepots = true
-	}
-
-	return dlcIDsSlice, hasDepots, nil
+	return nil, lastError
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// 测试用的应用信息源, 返回固定的详情
+type stubProvider struct {
+	name    string
+	details map[string]AppDetail
+}
+
+func (p stubProvider) Name() string { return p.name }
+
+func (p stubProvider) GetAppDetail(ctx context.Context, appid string) (*AppDetail, error) {
+	detail, ok := p.details[appid]
+	if !ok {
+		return nil, Err("provider.not_found", appid)
+	}
+	return &detail, nil
+}
+
+func setupProviders(t *testing.T, providers ...AppInfoProvider) {
+	t.Helper()
+	old := AppInfoProviders
+	AppInfoProviders = providers
+	t.Cleanup(func() { AppInfoProviders = old })
+}
+
+func TestAddDLCUnknownDepots(t *testing.T) {
+	quietLogs(t)
+	// 与商店接口一样不提供仓库信息的信息源
+	store := stubProvider{name: "store", details: map[string]AppDetail{
+		"10": {AppID: "10", Name: "Game", DLCs: []string{"20", "21"}},
+		"20": {AppID: "20", Name: "Expansion"},
+		"21": {AppID: "21", Name: "Soundtrack"},
+	}}
+	withDepots := stubProvider{name: "steamcmd", details: map[string]AppDetail{
+		"10": {AppID: "10", Name: "Game", DLCs: []string{"20", "21"}, DepotsKnown: true},
+		"20": {AppID: "20", Name: "Expansion", DepotsKnown: true},
+		"21": {AppID: "21", Name: "Soundtrack", HasDepots: true, DepotsKnown: true},
+	}}
+
+	filter := func(unknown string, deny string) *DLCFilter {
+		f := NewDLCFilter()
+		if err := f.SetUnknownDepots(unknown); err != nil {
+			t.Fatal(err)
+		}
+		if deny != "" {
+			f.SetList(f.Deny, "10", deny)
+		}
+		return f
+	}
+	tests := []struct {
+		name      string
+		provider  AppInfoProvider
+		filter    *DLCFilter
+		wantAdded []string
+		wantErr   bool
+	}{
+		{"unknown depots skipped without rules", store, nil, nil, true},
+		{"unknown depots skipped", store, filter("skip", ""), nil, true},
+		{"unknown depots added", store, filter("add", ""), []string{"20", "21"}, false},
+		{"added unknown depots still filtered", store, filter("add", "21"), []string{"20"}, false},
+		{"known depots ignore the option", withDepots, filter("add", ""), []string{"20"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupProviders(t, tt.provider)
+			path := filepath.Join(t.TempDir(), "10.lua")
+			if err := os.WriteFile(path, []byte("addappid(10)\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			added, detail, err := AddDLC(context.Background(), "10", path, tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddDLC error = %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(added, tt.wantAdded) {
+				t.Errorf("AddDLC added %v, want %v", added, tt.wantAdded)
+			}
+			if detail == nil || detail.Provider != tt.provider.Name() {
+				t.Errorf("AddDLC detail = %+v, want provider %s", detail, tt.provider.Name())
+			}
+		})
+	}
+}
+
+// 第一个信息源失败时使用下一个, 并记录实际提供信息的来源
+func TestGetAppDetailFallback(t *testing.T) {
+	setupProviders(t,
+		stubProvider{name: "steamcmd", details: map[string]AppDetail{}},
+		stubProvider{name: "store", details: map[string]AppDetail{"10": {AppID: "10", Name: "Game"}}},
+	)
+	detail, err := GetAppDetail(context.Background(), "10")
+	if err != nil || detail.Provider != "store" {
+		t.Fatalf("GetAppDetail = %+v, %v; want provider store", detail, err)
+	}
+
+	setupProviders(t)
+	if _, err := GetAppDetail(context.Background(), "10"); err == nil {
+		t.Fatal("GetAppDetail without providers succeeded")
+	}
+}
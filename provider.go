@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 应用信息源接口
+type AppInfoProvider interface {
+	Name() string
+	GetAppDetail(ctx context.Context, appid string) (*AppDetail, error)
+}
+
+// 根据名称创建应用信息源
+func NewAppInfoProvider(name string) (AppInfoProvider, error) {
+	switch strings.ToLower(name) {
+	case "steamcmd":
+		return steamCMDProvider{}, nil
+	case "store":
+		return steamStoreProvider{}, nil
+	}
+	return nil, Err("provider.unknown", name)
+}
+
+// steamcmd.net 信息源
+type steamCMDProvider struct{}
+
+func (steamCMDProvider) Name() string { return "steamcmd.net" }
+
+// 从 steamcmd.net 获取应用详情
+func (steamCMDProvider) GetAppDetail(ctx context.Context, appid string) (*AppDetail, error) {
+	url := fmt.Sprintf(DLCInfoURL, appid)
+	resp, err := fetch(ctx, httpClient, url, 0, maxJSONSize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != http.StatusOK {
+		return nil, Err("http.bad_status", resp.Status)
+	}
+
+	var info DLCInfo
+	if err := json.Unmarshal(resp.Body, &info); err != nil {
+		return nil, Err("http.json_failed", err)
+	}
+
+	appData, ok := info.Data[appid]
+	if !ok {
+		return nil, Err("provider.not_found", appid)
+	}
+
+	// 提取所有可能的DLC ID来源
+	dlcIDs := make(map[string]bool)
+
+	// 从common.listofdlc中提取
+	if listStr, ok := appData.Common["listofdlc"].(string); ok {
+		re := regexp.MustCompile(`\d+`)
+		matches := re.FindAllString(listStr, -1)
+		for _, id := range matches {
+			dlcIDs[id] = true
+		}
+	}
+
+	// 从extended.listofdlc中提取
+	if listStr, ok := appData.Extended["listofdlc"].(string); ok {
+		re := regexp.MustCompile(`\d+`)
+		matches := re.FindAllString(listStr, -1)
+		for _, id := range matches {
+			dlcIDs[id] = true
+		}
+	}
+
+	// 从depots.dlc列表中提取
+	if appData.Depots != nil {
+		if depotsMap, ok := appData.Depots.(map[string]interface{}); ok {
+			if dlcMap, ok := depotsMap["dlc"]; ok {
+				// dlcMap 可能是 map 也可能是其他类型
+				switch v := dlcMap.(type) {
+				case map[string]interface{}:
+					// 遍历这个map的键
+					for dlcID := range v {
+						dlcIDs[dlcID] = true
+					}
+				case string:
+					// 如果是字符串, 跳过或者记录日志
+					logger.Debug(T("provider.dlc_string", v), "appid", appid, "source", "steamcmd.net")
+				default:
+					logger.Debug(T("provider.dlc_type", v), "appid", appid, "source", "steamcmd.net")
+				}
+			}
+		} else {
+			logger.Debug(T("provider.depots_type", appData.Depots), "appid", appid, "source", "steamcmd.net")
+		}
+	}
+
+	// 从dlc字典中提取
+	for id := range appData.DLC {
+		dlcIDs[id] = true
+	}
+
+	// 转换为切片并排序
+	dlcIDsSlice := make([]string, 0, len(dlcIDs))
+	for id := range dlcIDs {
+		dlcIDsSlice = append(dlcIDsSlice, id)
+	}
+	sort.Slice(dlcIDsSlice, func(i, j int) bool {
+		a, _ := strconv.Atoi(dlcIDsSlice[i])
+		b, _ := strconv.Atoi(dlcIDsSlice[j])
+		return a < b
+	})
+
+	// 检查是否有仓库
+	hasDepots := false
+	if depots, ok := appData.Depots.(map[string]interface{}); ok && len(depots) > 0 {
+		hasDepots = true
+	} else if _, ok := appData.Depots.(string); ok {
+		// 字符串类型的depots也算有仓库
+		hasDepots = true
+	}
+
+	// 名称与类型
+	name, _ := appData.Common["name"].(string)
+	appType, _ := appData.Common["type"].(string)
+
+	return &AppDetail{
+		AppID:       appid,
+		Name:        name,
+		Type:        appType,
+		DLCs:        dlcIDsSlice,
+		HasDepots:   hasDepots,
+		DepotsKnown: true,
+	}, nil
+}
+
+// Steam 商店 appdetails 信息源
+type steamStoreProvider struct{}
+
+func (steamStoreProvider) Name() string { return "store.steampowered.com" }
+
+// 从 Steam 商店获取应用详情
+// 商店接口不提供仓库信息, DepotsKnown 为 false, 添加DLC时默认跳过这些DLC(dlcUnknownDepots = add 时添加)
+func (steamStoreProvider) GetAppDetail(ctx context.Context, appid string) (*AppDetail, error) {
+	url := fmt.Sprintf(StoreDetailsURL, appid)
+	resp, err := fetch(ctx, httpClient, url, 0, maxJSONSize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != http.StatusOK {
+		return nil, Err("http.bad_status", resp.Status)
+	}
+
+	var info StoreDetails
+	if err := json.Unmarshal(resp.Body, &info); err != nil {
+		return nil, Err("http.json_failed", err)
+	}
+
+	appData, ok := info[appid]
+	if !ok || !appData.Success {
+		return nil, Err("provider.not_found", appid)
+	}
+
+	dlcIDs := make([]string, 0, len(appData.Data.DLC))
+	for _, id := range appData.Data.DLC {
+		dlcIDs = append(dlcIDs, strconv.Itoa(id))
+	}
+	sort.Slice(dlcIDs, func(i, j int) bool {
+		a, _ := strconv.Atoi(dlcIDs[i])
+		b, _ := strconv.Atoi(dlcIDs[j])
+		return a < b
+	})
+
+	return &AppDetail{
+		AppID: appid,
+		Name:  appData.Data.Name,
+		Type:  appData.Data.Type,
+		DLCs:  dlcIDs,
+	}, nil
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// 类型显示名称的消息键
+var gameTypeLabels = map[string]string{
+	"game":        "search.type_game",
+	"dlc":         "search.type_dlc",
+	"application": "search.type_tool",
+	"tool":        "search.type_tool",
+	"music":       "search.type_music",
+	"video":       "search.type_video",
+	"demo":        "search.type_demo",
+}
+
+// 搜索结果中清单可用性的标记
+var availabilityMarks = map[Availability]string{
+	AvailabilityUnprobed:  "-",
+	AvailabilityUnknown:   "?",
+	AvailabilityMissing:   "✗",
+	AvailabilityAvailable: "✓",
+}
+
+// 按匹配程度排序并去重
+func RankGames(query string, games []Game) []Game {
+	type scored struct {
+		game  Game
+		score int
+	}
+
+	// 按AppID去重, 保留信息较全的条目
+	seen := make(map[int]int)
+	var list []scored
+	for _, game := range games {
+		if idx, ok := seen[game.AppID]; ok {
+			if list[idx].game.Type == "" && game.Type != "" {
+				list[idx].game.Type = game.Type
+			}
+			continue
+		}
+		seen[game.AppID] = len(list)
+		list = append(list, scored{game: game, score: matchScore(query, game.Name)})
+	}
+
+	// 分数高的在前, 同分时名称短的在前
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].score != list[j].score {
+			return list[i].score > list[j].score
+		}
+		return len([]rune(list[i].game.Name)) < len([]rune(list[j].game.Name))
+	})
+
+	ranked := make([]Game, len(list))
+	for i, item := range list {
+		ranked[i] = item.game
+	}
+	return ranked
+}
+
+// 计算名称与查询的匹配分数
+func matchScore(query, name string) int {
+	q := strings.Join(tokenize(query), " ")
+	n := strings.Join(tokenize(name), " ")
+	if q == "" || n == "" {
+		return 0
+	}
+
+	// 完全匹配、前缀匹配、包含匹配
+	switch {
+	case q == n:
+		return 1000
+	case strings.HasPrefix(n, q):
+		return 800
+	case strings.Contains(n, q):
+		return 600
+	}
+
+	score := 0
+
+	// 词元重合度
+	queryTokens := strings.Fields(q)
+	nameTokens := strings.Fields(n)
+	matched := 0
+	for _, qt := range queryTokens {
+		for _, nt := range nameTokens {
+			if strings.HasPrefix(nt, qt) || (len([]rune(qt)) >= 4 && levenshtein(qt, nt) <= 1) {
+				matched++
+				break
+			}
+		}
+	}
+	score = max(score, 400*matched/len(queryTokens))
+
+	// 整体编辑距离
+	longest := max(len([]rune(q)), len([]rune(n)))
+	similarity := 1 - float64(levenshtein(q, n))/float64(longest)
+	if similarity > 0.6 {
+		score = max(score, int(similarity*500))
+	}
+
+	// 中日韩文字没有空格分词, 按相邻字符对计算相似度
+	if hasCJK(q) {
+		score = max(score, int(bigramSimilarity(q, n)*500))
+	}
+
+	return score
+}
+
+// 判断是否包含中日韩文字
+func hasCJK(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+// 相邻字符对的 Dice 相似度
+func bigramSimilarity(a, b string) float64 {
+	bigrams := func(s string) map[string]int {
+		runes := []rune(strings.ReplaceAll(s, " ", ""))
+		set := make(map[string]int)
+		for i := 0; i+1 < len(runes); i++ {
+			set[string(runes[i:i+2])]++
+		}
+		return set
+	}
+
+	ba, bb := bigrams(a), bigrams(b)
+	total := 0
+	for _, c := range ba {
+		total += c
+	}
+	for _, c := range bb {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	common := 0
+	for k, c := range ba {
+		common += min(c, bb[k])
+	}
+	return float64(2*common) / float64(total)
+}
+
+// 补全缺少类型信息的游戏
+func fillGameTypes(ctx context.Context, games []Game) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 5) // 限制并发数
+	for i := range games {
+		if games[i].Type != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(game *Game) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if detail, err := GetAppDetail(ctx, strconv.Itoa(game.AppID)); err == nil {
+				game.Type = detail.Type
+			}
+		}(&games[i])
+	}
+	wg.Wait()
+}
+
+// 单个下载源检查清单的超时, 从取得并发槽位后开始计算
+const probeTimeout = 5 * time.Second
+
+// 探测游戏是否有可下载的清单(并行检查各下载源, 网络源发送 HEAD 请求, 本地源直接检查)
+// 离线模式下不访问网络, 只检查清单缓存
+func probeAvailability(ctx context.Context, config *Config, games []Game) {
+	if config.Offline {
+		probeCache(OpenCache(config), games)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 16) // 限制并发请求数
+	for i, game := range games {
+		appid := strconv.Itoa(game.AppID)
+		// 任一源有清单即视为可用, 并取消其余请求
+		gameCtx, cancel := context.WithCancel(ctx)
+		results := make([]Availability, len(Sources))
+		var gameWg sync.WaitGroup
+		for j, source := range Sources {
+			prober, ok := source.(sourceProber)
+			if !ok {
+				results[j] = AvailabilityUnknown
+				continue
+			}
+			gameWg.Add(1)
+			go func() {
+				defer gameWg.Done()
+				select {
+				case sem <- struct{}{}:
+				case <-gameCtx.Done():
+					results[j] = AvailabilityUnknown
+					return
+				}
+				defer func() { <-sem }()
+				probeCtx, probeCancel := context.WithTimeout(gameCtx, probeTimeout)
+				defer probeCancel()
+				results[j] = prober.Probe(probeCtx, appid)
+				if results[j] == AvailabilityAvailable {
+					cancel()
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gameWg.Wait()
+			cancel()
+			games[i].Availability = combineAvailability(results)
+		}()
+	}
+	wg.Wait()
+}
+
+// 合并各下载源的检查结果: 任一源有清单即可用, 所有源都确认没有才视为没有清单, 其余无法确定
+func combineAvailability(results []Availability) Availability {
+	if len(results) == 0 {
+		return AvailabilityUnknown
+	}
+	combined := AvailabilityMissing
+	for _, result := range results {
+		switch result {
+		case AvailabilityAvailable:
+			return AvailabilityAvailable
+		case AvailabilityMissing:
+		default:
+			combined = AvailabilityUnknown
+		}
+	}
+	return combined
+}
+
+// 按清单缓存标记可用性(离线模式只能使用缓存中的清单)
+func probeCache(cache *ManifestCache, games []Game) {
+	for i := range games {
+		if cache == nil {
+			games[i].Availability = AvailabilityMissing
+			continue
+		}
+		entry, err := cache.Entry(strconv.Itoa(games[i].AppID))
+		switch {
+		case err != nil:
+			games[i].Availability = AvailabilityUnknown
+		case entry == nil:
+			games[i].Availability = AvailabilityMissing
+		default:
+			games[i].Availability = AvailabilityAvailable
+		}
+	}
+}
+
+// 发送 HEAD 请求: 200 为有清单, 404 为没有清单, 其余情况(超时、网络错误、不支持 HEAD 等)无法确定
+func headAvailability(ctx context.Context, url string) Availability {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return AvailabilityUnknown
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return AvailabilityUnknown
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return AvailabilityAvailable
+	case http.StatusNotFound:
+		return AvailabilityMissing
+	}
+	return AvailabilityUnknown
+}
+
+// 输出一页搜索结果
+func PrintGames(ctx context.Context, config *Config, games []Game, offset, limit int) int {
+	end := min(offset+limit, len(games))
+	page := games[offset:end]
+
+	// 本地索引模式下不联网补全类型
+	if config.SearchMode != SearchModeLocal {
+		fillGameTypes(ctx, page)
+	}
+
+	// 探测各结果是否有可下载的清单
+	if config.ProbeResults {
+		logger.Info(T("search.probing"), "step", "search")
+		probeAvailability(ctx, config, page)
+	}
+
+	// ManifestHub 的 depotkeys.json 中存在的AppID视为已收录
+	depotkeys, err := GetDepotkeys(ctx)
+	if ctx.Err() != nil {
+		return offset
+	}
+	if err != nil {
+		logger.Warn(T("search.known_failed", err), "step", "search")
+	}
+
+	for i, game := range page {
+		gameType := "-"
+		if game.Type != "" {
+			gameType = game.Type
+			if label, ok := gameTypeLabels[strings.ToLower(game.Type)]; ok {
+				gameType = T(label)
+			}
+		}
+
+		known := "?"
+		if depotkeys != nil {
+			known = T("common.no")
+			if _, ok := depotkeys[strconv.Itoa(game.AppID)]; ok {
+				known = T("common.yes")
+			}
+		}
+
+		manifest := availabilityMarks[game.Availability]
+
+		// 格式化输出，对齐显示
+		fmt.Fprintf(console, T("search.row"),
+			offset+i+1, manifest, game.Name, game.AppID, gameType, known)
+	}
+	return end
+}
+
+// 将名称规范化为小写词元
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// 计算编辑距离
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		gameName string
+		want     int
+	}{
+		{"exact ignoring case and punctuation", "portal 2", "Portal-2", 1000},
+		{"prefix", "portal", "Portal 2", 800},
+		{"contains", "strike", "Counter-Strike 2", 600},
+		{"empty query", "  ", "Portal", 0},
+		{"unrelated", "portal", "Half-Life", 0},
+		{"typo in one token", "cyberpumk 2077", "Cyberpunk 2077", 464},
+		{"token prefixes in another order", "souls dark", "Dark Souls III", 400},
+		{"CJK without separators", "黑神话悟空", "黑神话：悟空", 500},
+		{"CJK with a missing character", "艾尔登环", "艾尔登法环", 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchScore(tt.query, tt.gameName); got != tt.want {
+				t.Errorf("matchScore(%q, %q) = %d, want %d", tt.query, tt.gameName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankGames(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		games []Game
+		want  []int // 排序后的 AppID
+	}{
+		{
+			name:  "better match first",
+			query: "portal",
+			games: []Game{{AppID: 1, Name: "Portal Stories: Mel"}, {AppID: 2, Name: "Half-Life"}, {AppID: 3, Name: "Portal"}},
+			want:  []int{3, 1, 2},
+		},
+		{
+			name:  "shorter name first on equal score",
+			query: "portal",
+			games: []Game{{AppID: 1, Name: "Portal Reloaded"}, {AppID: 2, Name: "Portal 2"}},
+			want:  []int{2, 1},
+		},
+		{
+			name:  "typo still ranks the intended game first",
+			query: "cyberpumk",
+			games: []Game{{AppID: 1, Name: "Cyber Hook"}, {AppID: 2, Name: "Cyberpunk 2077"}},
+			want:  []int{2, 1},
+		},
+		{
+			name:  "CJK",
+			query: "黑神话悟空",
+			games: []Game{{AppID: 1, Name: "悟空传"}, {AppID: 2, Name: "黑神话：悟空"}},
+			want:  []int{2, 1},
+		},
+		{
+			name:  "duplicates removed",
+			query: "portal",
+			games: []Game{{AppID: 1, Name: "Portal"}, {AppID: 1, Name: "Portal"}, {AppID: 2, Name: "Portal 2"}},
+			want:  []int{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, game := range RankGames(tt.query, tt.games) {
+				got = append(got, game.AppID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RankGames(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// 去重时保留有类型信息的条目
+func TestRankGamesKeepsType(t *testing.T) {
+	games := RankGames("portal", []Game{{AppID: 1, Name: "Portal"}, {AppID: 1, Name: "Portal", Type: "game"}})
+	if len(games) != 1 || games[0].Type != "game" {
+		t.Fatalf("RankGames = %+v, want one entry with type game", games)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serve 命令的默认设置
+const (
+	defaultServeAddr        = "127.0.0.1:8080"
+	defaultServeConcurrency = 4
+	serveFreshFor           = 10 * time.Minute // 下载库中在此时间内下载过的清单直接返回, 不重新下载
+	serveMaxSearchResults   = 50
+)
+
+// 等待空闲处理槽位的最长时间, 超时返回 503
+var serveQueueTimeout = 30 * time.Second
+
+// HTTP 接口服务
+type apiServer struct {
+	config   *Config
+	slots    chan struct{} // 限制同时处理的请求数
+	appLocks sync.Map      // AppID -> *sync.Mutex, 同一游戏的下载串行执行
+	ui       bool          // 是否提供网页界面
+}
+
+// 执行 serve 命令: 以 HTTP 接口提供下载、搜索、DLC 及 DepotKey 查询, ctx 取消时停止服务
+func RunServe(ctx context.Context, config *Config, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	addr := flags.String("addr", defaultServeAddr, "")
+	concurrency := flags.Int("max-concurrent", defaultServeConcurrency, "")
+	ui := flags.Bool("ui", false, "")
+	if err := flags.Parse(args); err != nil {
+		return usageError(Err("serve.bad_args", err))
+	}
+	if *concurrency <= 0 {
+		return markError(ErrInvalidInput, Err("serve.bad_concurrency", *concurrency))
+	}
+
+	server := &apiServer{config: config, slots: make(chan struct{}, *concurrency), ui: *ui}
+	httpServer := &http.Server{
+		Handler:           server.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return Err("serve.listen_failed", err)
+	}
+	logger.Info(T("serve.listening", "http://"+listener.Addr().String(), *concurrency), "step", "serve")
+	if *ui {
+		logger.Info(T("serve.ui", "http://"+listener.Addr().String()+"/"), "step", "serve")
+	}
+
+	// 收到中断信号后不再接受新请求, 等待处理中的请求结束
+	go func() {
+		<-ctx.Done()
+		logger.Info(T("serve.stopping"), "step", "serve")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return Err("serve.failed", err)
+	}
+	return nil
+}
+
+// 注册接口
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /apps/{file}", s.limit(s.handleManifest))
+	mux.Handle("GET /apps/{appid}/dlc", s.limit(s.handleDLC))
+	mux.Handle("GET /search", s.limit(s.handleSearch))
+	mux.Handle("GET /depotkeys/{id}", s.limit(s.handleDepotkey))
+	if s.ui {
+		s.uiRoutes(mux)
+	}
+	return logRequests(mux)
+}
+
+// 限制同时处理的请求数, 等待超时后返回 503
+func (s *apiServer) limit(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timer := time.NewTimer(serveQueueTimeout)
+		defer timer.Stop()
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+			handler(w, r)
+		case <-timer.C:
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(serveQueueTimeout.Seconds()))))
+			writeJSONError(w, http.StatusServiceUnavailable, Err("serve.busy"))
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// GET /apps/{appid}.lua: 处理后的清单, 近期下载过时直接返回下载路径中的文件
+func (s *apiServer) handleManifest(w http.ResponseWriter, r *http.Request) {
+	appid, ok := strings.CutSuffix(r.PathValue("file"), ".lua")
+	if !ok || !isAppID(appid) {
+		writeJSONError(w, http.StatusNotFound, Err("serve.not_found", r.URL.Path))
+		return
+	}
+
+	lock, _ := s.appLocks.LoadOrStore(appid, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	source := ""
+	data, modTime, ok := s.freshManifest(appid)
+	if !ok {
+		result, err := Download(r.Context(), appid, s.config)
+		if err != nil {
+			writeJSONError(w, httpStatus(err), err)
+			return
+		}
+		if data, err = os.ReadFile(result.OutputPath); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		source, modTime = result.Source, time.Now()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+appid+`.lua"`)
+	w.Header().Set("ETag", `"`+sha256Hex(data)+`"`)
+	if source != "" {
+		w.Header().Set("X-Manifest-Source", source)
+	}
+	http.ServeContent(w, r, appid+".lua", modTime, bytes.NewReader(data))
+}
+
+// 下载库中近期下载过且文件未被改动的清单
+func (s *apiServer) freshManifest(appid string) ([]byte, time.Time, bool) {
+	library, err := LoadLibrary(s.config.DownloadPath)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	entry := library.Get(appid)
+	if entry == nil || time.Since(entry.DownloadedAt) > serveFreshFor {
+		return nil, time.Time{}, false
+	}
+	data, err := os.ReadFile(filepath.Join(s.config.DownloadPath, appid+".lua"))
+	if err != nil || sha256Hex(data) != entry.SHA256 {
+		return nil, time.Time{}, false
+	}
+	return data, entry.DownloadedAt, true
+}
+
+// 接口返回的DLC信息
+type serveDLC struct {
+	AppID     string `json:"appid"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type,omitempty"`
+	HasDepots *bool  `json:"has_depots,omitempty"`
+}
+
+// GET /apps/{appid}/dlc: 游戏的DLC列表, details=1 时获取每个DLC的名称和类型
+func (s *apiServer) handleDLC(w http.ResponseWriter, r *http.Request) {
+	appid := r.PathValue("appid")
+	if !isAppID(appid) {
+		writeJSONError(w, http.StatusBadRequest, markError(ErrInvalidInput, Err("input.invalid")))
+		return
+	}
+	detail, err := GetAppDetail(r.Context(), appid)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+	// 信息源不提供仓库信息时 has_depots 为 null
+	var hasDepots *bool
+	if detail.DepotsKnown {
+		hasDepots = &detail.HasDepots
+	}
+
+	dlcs := make([]serveDLC, len(detail.DLCs))
+	for i, id := range detail.DLCs {
+		dlcs[i].AppID = id
+	}
+	if r.URL.Query().Get("details") == "1" {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, 8) // 限制并发请求数
+		for i := range dlcs {
+			wg.Add(1)
+			go func(dlc *serveDLC) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				if detail, err := GetAppDetail(r.Context(), dlc.AppID); err == nil {
+					dlc.Name, dlc.Type = detail.Name, detail.Type
+					if detail.DepotsKnown {
+						dlc.HasDepots = &detail.HasDepots
+					}
+				}
+			}(&dlcs[i])
+		}
+		wg.Wait()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"appid": appid, "has_depots": hasDepots, "dlcs": dlcs})
+}
+
+// 接口返回的搜索结果
+type serveGame struct {
+	Game
+	HasKey    *bool `json:"has_key,omitempty"`   // depotkeys.json 中是否有该游戏, 获取失败时省略
+	Available *bool `json:"available,omitempty"` // 下载源中是否有清单, 仅在 probe=1 且能确定时返回
+}
+
+// GET /search?q=: 按名称搜索, limit 指定结果数, probe=1 时检查各结果的清单是否可用
+func (s *apiServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if strings.TrimSpace(query.Get("q")) == "" {
+		writeJSONError(w, http.StatusBadRequest, markError(ErrInvalidInput, Err("search.empty_name")))
+		return
+	}
+	limit := s.config.SearchLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, markError(ErrInvalidInput, Err("serve.bad_limit", value)))
+			return
+		}
+		limit = min(n, serveMaxSearchResults)
+	}
+
+	games, err := FindAppID(r.Context(), s.config, query.Get("q"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+	games = games[:min(limit, len(games))]
+	if query.Get("probe") == "1" {
+		probeAvailability(r.Context(), s.config, games)
+	}
+
+	depotkeys, _ := GetDepotkeys(r.Context())
+	results := make([]serveGame, len(games))
+	for i, game := range games {
+		results[i].Game = game
+		if depotkeys != nil {
+			_, hasKey := depotkeys[strconv.Itoa(game.AppID)]
+			results[i].HasKey = &hasKey
+		}
+		// 无法确定时省略
+		switch game.Availability {
+		case AvailabilityAvailable, AvailabilityMissing:
+			available := game.Availability == AvailabilityAvailable
+			results[i].Available = &available
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// GET /depotkeys/{id}: 查询 DepotKey
+func (s *apiServer) handleDepotkey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	depotkeys, err := GetDepotkeys(r.Context())
+	if err != nil {
+		writeJSONError(w, httpStatus(err), err)
+		return
+	}
+	key, err := LookupDepotkey(id, depotkeys)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "key": key})
+}
+
+// 是否为纯数字的 AppID
+func isAppID(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
+
+// 按错误类型选择 HTTP 状态码
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrManifestNotFound), errors.Is(err, ErrNoDepotKey):
+		return http.StatusNotFound
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrNetwork), errors.Is(err, ErrAllSourcesFailed), errors.Is(err, ErrInvalidZip):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// 记录每个请求的方法、路径、状态码及耗时
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		logger.Info(T("serve.request", r.Method, r.URL.RequestURI(), recorder.status, time.Since(start).Milliseconds()),
+			"step", "serve", "remote", r.RemoteAddr)
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 使用返回 404 的测试服务器作为唯一的下载源, 返回 API 服务及下载源收到的请求数
+func setupServeTest(t *testing.T, concurrency int) (*apiServer, *atomic.Int32) {
+	t.Helper()
+	quietLogs(t)
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(upstream.Close)
+	oldSources := Sources
+	Sources = []Source{urlSource{upstream.URL + "/{appid}.lua"}}
+	t.Cleanup(func() { Sources = oldSources })
+
+	config := &Config{DownloadPath: t.TempDir()}
+	return &apiServer{config: config, slots: make(chan struct{}, concurrency)}, &requests
+}
+
+// 发送请求, 返回响应及 JSON 错误信息(没有时为空)
+func serveRequest(t *testing.T, server *apiServer, target string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.routes().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	var body struct {
+		Error string `json:"error"`
+	}
+	if recorder.Header().Get("Content-Type") == "application/json; charset=utf-8" {
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+	}
+	return recorder, body.Error
+}
+
+func TestServeRejectsInvalidAppID(t *testing.T) {
+	server, requests := setupServeTest(t, 1)
+	tests := []struct {
+		target     string
+		wantStatus int
+	}{
+		{"/apps/abc.lua", http.StatusNotFound},
+		{"/apps/730.txt", http.StatusNotFound},
+		{"/apps/-1.lua", http.StatusNotFound},
+		{"/apps/99999999999.lua", http.StatusNotFound},
+		{"/apps/abc/dlc", http.StatusBadRequest},
+		{"/apps/7%2030/dlc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			recorder, errMsg := serveRequest(t, server, tt.target)
+			if recorder.Code != tt.wantStatus || errMsg == "" {
+				t.Errorf("GET %s = %d %q, want %d with an error", tt.target, recorder.Code, errMsg, tt.wantStatus)
+			}
+		})
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("sources received %d requests for invalid AppIDs", n)
+	}
+}
+
+func TestServeManifestNotFound(t *testing.T) {
+	server, requests := setupServeTest(t, 1)
+	recorder, errMsg := serveRequest(t, server, "/apps/10.lua")
+	if recorder.Code != http.StatusNotFound || errMsg == "" {
+		t.Fatalf("GET /apps/10.lua = %d %q, want 404 with an error", recorder.Code, errMsg)
+	}
+	if requests.Load() == 0 {
+		t.Error("sources were not queried")
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"invalid input", markError(ErrInvalidInput, errors.New("bad")), http.StatusBadRequest},
+		{"manifest not found", markError(ErrManifestNotFound, errors.New("missing")), http.StatusNotFound},
+		{"all sources not found", sourcesError(statusError(errors.New("404"), http.StatusNotFound)), http.StatusNotFound},
+		{"no depot key", markError(ErrNoDepotKey, errors.New("no key")), http.StatusNotFound},
+		{"canceled", Err("cancel.canceled", context.Canceled), http.StatusServiceUnavailable},
+		{"network", markError(ErrNetwork, errors.New("timeout")), http.StatusBadGateway},
+		{"mixed source failures", sourcesError(errors.New("500")), http.StatusBadGateway},
+		{"invalid zip", markError(ErrInvalidZip, errors.New("bad zip")), http.StatusBadGateway},
+		{"other", errors.New("boom"), http.StatusInternalServerError},
+		{"wrapped", fmt.Errorf("download: %w", markError(ErrManifestNotFound, errors.New("missing"))), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := httpStatus(tt.err); got != tt.want {
+				t.Errorf("httpStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// 没有空闲的处理槽位时, 等待超时后返回 503
+func TestServeBusy(t *testing.T) {
+	server, requests := setupServeTest(t, 1)
+	old := serveQueueTimeout
+	serveQueueTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { serveQueueTimeout = old })
+
+	server.slots <- struct{}{} // 占用唯一的槽位
+	recorder, errMsg := serveRequest(t, server, "/apps/10.lua")
+	if recorder.Code != http.StatusServiceUnavailable || errMsg != Err("serve.busy").Error() || recorder.Header().Get("Retry-After") == "" {
+		t.Fatalf("GET while busy = %d %q, Retry-After %q; want 503", recorder.Code, errMsg, recorder.Header().Get("Retry-After"))
+	}
+	if requests.Load() != 0 {
+		t.Error("request was processed while busy")
+	}
+
+	// 槽位释放后正常处理
+	<-server.slots
+	if recorder, _ := serveRequest(t, server, "/apps/10.lua"); recorder.Code != http.StatusNotFound {
+		t.Fatalf("GET after slot freed = %d, want 404", recorder.Code)
+	}
+}
+
+// 下载库中近期下载过且未被改动的清单直接返回, 不访问下载源
+func TestServeFreshManifest(t *testing.T) {
+	content := []byte("addappid(10)\n")
+	tests := []struct {
+		name         string
+		downloadedAt time.Duration // 距现在的时间
+		fileContent  []byte
+		wantStatus   int
+		wantSources  bool // 是否访问了下载源
+	}{
+		{"fresh", -time.Minute, content, http.StatusOK, false},
+		{"stale", -2 * serveFreshFor, content, http.StatusNotFound, true},
+		{"file changed", -time.Minute, []byte("addappid(10)\naddappid(20)\n"), http.StatusNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := setupServeTest(t, 1)
+			dir := server.config.DownloadPath
+			library := &Library{Apps: []LibraryEntry{{AppID: "10", DownloadedAt: time.Now().Add(tt.downloadedAt), SHA256: sha256Hex(content)}}}
+			if err := library.Save(dir); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "10.lua"), tt.fileContent, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			recorder, _ := serveRequest(t, server, "/apps/10.lua")
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("GET /apps/10.lua = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if got := requests.Load() > 0; got != tt.wantSources {
+				t.Errorf("sources queried = %v, want %v", got, tt.wantSources)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if recorder.Body.String() != string(content) || recorder.Header().Get("ETag") != `"`+sha256Hex(content)+`"` {
+					t.Errorf("body = %q, ETag %s", recorder.Body.String(), recorder.Header().Get("ETag"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 清单下载源接口
+// Fetch 失败时返回的 SourcePayload 可以非空, 用于记录实际地址和状态码
+type Source interface {
+	Name() string
+	Fetch(ctx context.Context, appid string) (*SourcePayload, error)
+}
+
+// 支持条件请求的下载源, 缓存记录来自同一地址时只在内容变化后重新下载
+// 内容未变化时返回 NotModified 为 true 且不含数据的 SourcePayload
+type conditionalSource interface {
+	FetchIfModified(ctx context.Context, appid string, cached *CacheEntry) (*SourcePayload, error)
+}
+
+// 可以快速检查清单是否存在的下载源(搜索结果的可用性标记)
+type sourceProber interface {
+	Probe(ctx context.Context, appid string) Availability
+}
+
+// 下载源返回的清单及元数据
+type SourcePayload struct {
+	Data        []byte // <AppID>.lua 的内容
+	URL         string // 实际请求的地址或路径
+	Status      int    // HTTP 状态码, 非 HTTP 源为 0
+	ETag        string // 响应的 ETag, 可能为空
+	Archive     string // 清单来自 ZIP 时压缩包的路径
+	TempArchive bool   // 压缩包是否为临时下载的文件(使用后删除)
+	NotModified bool   // 条件请求确认内容与缓存相同
+}
+
+// 下载源配置的前缀
+const (
+	gitSourcePrefix = "git:" // 本地 ManifestHub 仓库, 如 git:/srv/ManifestHub
+	dirSourcePrefix = "dir:" // 本地目录, 如 dir:/data/manifests
+	zipSourcePrefix = "zip:" // 每个 AppID 一个 ZIP 的地址模板
+)
+
+// 根据配置创建下载源; http(s) 地址为 Lua 文件的地址模板, 其余需要带前缀
+func NewSource(spec string) (Source, error) {
+	lower := strings.ToLower(spec)
+	switch {
+	case strings.HasPrefix(lower, gitSourcePrefix):
+		return gitSource{repo: spec[len(gitSourcePrefix):]}, nil
+	case strings.HasPrefix(lower, dirSourcePrefix):
+		return dirSource{dir: spec[len(dirSourcePrefix):]}, nil
+	case strings.HasPrefix(lower, zipSourcePrefix):
+		return zipArchiveSource{template: spec[len(zipSourcePrefix):]}, nil
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return urlSource{template: spec}, nil
+	}
+	return nil, Err("source.unknown", spec)
+}
+
+// 地址模板中的 %s 或 {appid} 替换为 AppID
+func expandTemplate(template, appid string) string {
+	return strings.NewReplacer("%s", appid, "{appid}", appid).Replace(template)
+}
+
+// 直接下载 <AppID>.lua 的地址模板
+type urlSource struct{ template string }
+
+func (s urlSource) Name() string { return s.template }
+
+// 下载 Lua 文件
+func (s urlSource) Fetch(ctx context.Context, appid string) (*SourcePayload, error) {
+	return s.FetchIfModified(ctx, appid, nil)
+}
+
+// 缓存记录来自同一地址且有 ETag 时发送 If-None-Match
+func (s urlSource) FetchIfModified(ctx context.Context, appid string, cached *CacheEntry) (*SourcePayload, error) {
+	url := expandTemplate(s.template, appid)
+	var header http.Header
+	if cached != nil && cached.Source == url && cached.ETag != "" {
+		header = http.Header{"If-None-Match": {cached.ETag}}
+	}
+	resp, err := fetchWithHeader(ctx, httpClient, url, header, 3*time.Second, maxManifestSize)
+	if err != nil {
+		payload := &SourcePayload{URL: url}
+		if resp != nil {
+			payload.Status = resp.Status
+		}
+		return payload, err
+	}
+	payload := &SourcePayload{URL: url, Status: resp.Status, ETag: resp.Header.Get("ETag")}
+	if resp.Status == http.StatusNotModified && header != nil {
+		payload.NotModified = true
+		payload.ETag = cached.ETag
+		return payload, nil
+	}
+	if resp.Status != http.StatusOK {
+		return payload, statusError(Err("http.bad_status", resp.Status), resp.Status)
+	}
+	payload.Data = resp.Body
+	return payload, nil
+}
+
+func (s urlSource) Probe(ctx context.Context, appid string) Availability {
+	return headAvailability(ctx, expandTemplate(s.template, appid))
+}
+
+// 每个 AppID 一个 ZIP 的地址模板, 下载后从中读取 <AppID>.lua
+type zipArchiveSource struct{ template string }
+
+func (s zipArchiveSource) Name() string { return zipSourcePrefix + s.template }
+
+// 下载压缩包并读取清单, 压缩包保留给后续的解压步骤
+func (s zipArchiveSource) Fetch(ctx context.Context, appid string) (*SourcePayload, error) {
+	zipURL := expandTemplate(s.template, appid)
+	logger.Info(T("zip.trying", zipURL), "appid", appid, "source", zipURL, "step", "fetch")
+	data, archive, err := tryZipSource(ctx, zipURL, appid)
+	if err != nil {
+		return &SourcePayload{URL: zipURL}, err
+	}
+	return &SourcePayload{Data: data, URL: zipURL, Status: http.StatusOK, Archive: archive, TempArchive: true}, nil
+}
+
+func (s zipArchiveSource) Probe(ctx context.Context, appid string) Availability {
+	return headAvailability(ctx, expandTemplate(s.template, appid))
+}
+
+// 本地目录, 依次查找 <AppID>.lua、<AppID>/<AppID>.lua 和 <AppID>.zip
+type dirSource struct{ dir string }
+
+func (s dirSource) Name() string { return dirSourcePrefix + s.dir }
+
+// 目录中可能的清单文件
+func (s dirSource) candidates(appid string) []string {
+	return []string{
+		filepath.Join(s.dir, appid+".lua"),
+		filepath.Join(s.dir, appid, appid+".lua"),
+		filepath.Join(s.dir, appid+".zip"),
+	}
+}
+
+// 读取找到的第一个清单文件
+func (s dirSource) Fetch(ctx context.Context, appid string) (*SourcePayload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, Err("cancel.canceled", err)
+	}
+	for _, path := range s.candidates(appid) {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		data, archive, err := readLocalFile(path, info, appid)
+		if err != nil {
+			return &SourcePayload{URL: path}, err
+		}
+		return &SourcePayload{Data: data, URL: path, Archive: archive}, nil
+	}
+	return &SourcePayload{URL: s.dir}, markError(ErrManifestNotFound, Err("source.dir_not_found", appid, s.dir))
+}
+
+func (s dirSource) Probe(ctx context.Context, appid string) Availability {
+	for _, path := range s.candidates(appid) {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return AvailabilityAvailable
+		}
+	}
+	return AvailabilityMissing
+}
+
+// 本地 ManifestHub git 仓库
+type gitSource struct{ repo string }
+
+func (s gitSource) Name() string { return gitSourcePrefix + s.repo }
+
+// 从仓库的 <AppID> 分支读取清单
+func (s gitSource) Fetch(ctx context.Context, appid string) (*SourcePayload, error) {
+	data, err := ReadGitSource(ctx, s.repo, appid)
+	if err != nil {
+		return &SourcePayload{URL: s.Name()}, err
+	}
+	return &SourcePayload{Data: data, URL: s.Name()}, nil
+}
+
+func (s gitSource) Probe(ctx context.Context, appid string) Availability {
+	return gitAvailability(ctx, s.repo, appid)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 更新检查的状态
+const (
+	UpdateUpdated   = "updated"   // 上游有变化, 已重新下载
+	UpdateOutdated  = "outdated"  // 上游有变化, 仅检查未下载
+	UpdateUnchanged = "unchanged" // 与上游相同
+	UpdateFailed    = "failed"    // 检查或下载失败
+)
+
+// 单个游戏的更新检查结果
+type UpdateResult struct {
+	AppID            string          `json:"appid"`
+	Status           string          `json:"status"`
+	ManifestsChanged int             `json:"manifests_changed"`    // ManifestID 变化的 Depot 数
+	OldSHA256        string          `json:"old_sha256,omitempty"` // 上次下载时上游内容的哈希(来自缓存)
+	NewSHA256        string          `json:"new_sha256,omitempty"` // 当前上游内容的哈希
+	Source           string          `json:"source,omitempty"`     // 下载源
+	Download         *DownloadResult `json:"download,omitempty"`   // 重新下载的结果
+	Error            string          `json:"error,omitempty"`
+	ExitCode         int             `json:"exit_code,omitempty"`
+}
+
+// 执行 update 命令: 检查下载路径中的所有 <AppID>.lua, 只重新下载上游有变化的游戏
+// --check 时只报告不下载
+func RunUpdate(ctx context.Context, config *Config, args []string, out io.Writer) error {
+	checkOnly := false
+	for _, arg := range args {
+		if arg != "--check" {
+			return usageError(Err("cmd.unknown", arg))
+		}
+		checkOnly = true
+	}
+	if config.Offline {
+		return markError(ErrInvalidInput, Err("update.offline"))
+	}
+
+	appids, err := scanDownloads(config.DownloadPath)
+	if err != nil {
+		return err
+	}
+	logger.Info(T("update.found", len(appids), config.DownloadPath), "step", "update")
+
+	cache := OpenCache(config)
+	var results []*UpdateResult
+	var firstErr error
+	failed := 0
+	for _, appid := range appids {
+		if ctx.Err() != nil {
+			break
+		}
+		printDivision()
+		result, err := checkUpdate(ctx, config, cache, appid, checkOnly)
+		if err != nil {
+			failed++
+			result.Status = UpdateFailed
+			result.Error = err.Error()
+			result.ExitCode = ExitCode(err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			logger.Error(T("update.check_failed", err), "appid", appid, "step", "update")
+		}
+
+		// NDJSON 每检查完一个就输出一行
+		if config.Output == OutputNDJSON {
+			if err := writeRecord(out, config.Output, result); err != nil {
+				return err
+			}
+		}
+		results = append(results, result)
+	}
+
+	switch config.Output {
+	case OutputJSON:
+		if err := writeRecords(out, config.Output, results); err != nil {
+			return err
+		}
+	case OutputText:
+		fmt.Fprintln(out, Division)
+		counts := map[string]int{}
+		for _, result := range results {
+			counts[result.Status]++
+			switch result.Status {
+			case UpdateUpdated:
+				fmt.Fprintln(out, T("update.row_updated", result.AppID, result.ManifestsChanged))
+			case UpdateOutdated:
+				fmt.Fprintln(out, T("update.row_outdated", result.AppID, result.ManifestsChanged))
+			case UpdateUnchanged:
+				fmt.Fprintln(out, T("update.row_unchanged", result.AppID))
+			default:
+				fmt.Fprintln(out, T("update.row_failed", result.AppID, result.Error))
+			}
+		}
+		fmt.Fprintln(out, T("update.summary", len(results), counts[UpdateUpdated]+counts[UpdateOutdated], counts[UpdateUnchanged], counts[UpdateFailed]))
+	}
+
+	if failed > 0 {
+		return Err("update.failed", failed, len(results), firstErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return Err("cancel.canceled", err)
+	}
+	return nil
+}
+
+// 下载路径中已下载的 AppID(文件名为 <AppID>.lua), 按数值排序
+func scanDownloads(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, Err("update.scan_failed", err)
+	}
+	var appids []string
+	for _, file := range files {
+		if m := luaEntryRegex.FindStringSubmatch(file.Name()); m != nil && file.Type().IsRegular() {
+			appids = append(appids, m[1])
+		}
+	}
+	sort.Slice(appids, func(i, j int) bool { return appIDLess(appids[i], appids[j]) })
+	return appids, nil
+}
+
+// 获取上游清单并与已下载的版本比较, 有变化时使用获取的内容重新执行下载流程
+// 有缓存记录时比较上游内容的哈希, 同时比较各 Depot 的 ManifestID
+func checkUpdate(ctx context.Context, config *Config, cache *ManifestCache, appid string, checkOnly bool) (*UpdateResult, error) {
+	result := &UpdateResult{AppID: appid}
+	logger.Info(T("update.checking", appid), "appid", appid, "step", "update")
+
+	localData, err := os.ReadFile(filepath.Join(config.DownloadPath, appid+".lua"))
+	if err != nil {
+		return result, Err("update.read_failed", err)
+	}
+	var previous *CacheEntry
+	if cache != nil {
+		if previous, err = cache.Entry(appid); err != nil {
+			logger.Warn(T("cache.ignored", err), "appid", appid, "step", "update")
+		}
+	}
+
+	fetched, err := TrySources(ctx, appid, cache)
+	if err != nil {
+		logSourcesReport(err, "appid", appid, "step", "fetch")
+		return result, err
+	}
+	result.Source = fetched.Source
+	result.NewSHA256 = sha256Hex(fetched.Data)
+	result.ManifestsChanged = changedManifests(ParseManifest(localData), ParseManifest(fetched.Data))
+	if previous != nil {
+		result.OldSHA256 = previous.SHA256
+	}
+
+	changed := result.ManifestsChanged > 0 || (previous != nil && previous.SHA256 != result.NewSHA256)
+	if !changed || checkOnly {
+		if fetched.TempArchive {
+			os.Remove(fetched.Archive)
+		}
+		if changed {
+			// 只检查时恢复缓存记录, 下次仍能发现变化
+			if previous != nil {
+				if err := cache.writeEntry(previous); err != nil {
+					logger.Warn(T("cache.ignored", err), "appid", appid, "step", "update")
+				}
+			}
+			result.Status = UpdateOutdated
+			logger.Info(T("update.outdated", result.ManifestsChanged), "appid", appid, "step", "update")
+		} else {
+			result.Status = UpdateUnchanged
+			logger.Info(T("update.unchanged"), "appid", appid, "step", "update")
+		}
+		return result, nil
+	}
+
+	logger.Info(T("update.redownloading", result.ManifestsChanged), "appid", appid, "step", "update")
+	download, err := runPipeline(ctx, appid, config, func(context.Context) (*FetchResult, error) {
+		return fetched, nil
+	})
+	result.Download = download
+	if err != nil {
+		return result, err
+	}
+	result.Status = UpdateUpdated
+	return result, nil
+}
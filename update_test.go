@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// 使用测试服务器作为唯一的下载源, 返回的内容为 upstream
+func setupUpdateTest(t *testing.T, upstream []byte) *Config {
+	t.Helper()
+	quietLogs(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if upstream == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(upstream)
+	}))
+	t.Cleanup(server.Close)
+	oldSources := Sources
+	Sources = []Source{urlSource{server.URL + "/{appid}.lua"}}
+	t.Cleanup(func() { Sources = oldSources })
+	return &Config{DownloadPath: t.TempDir()}
+}
+
+func TestCheckUpdate(t *testing.T) {
+	local := []byte("addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\n")
+	newManifest := []byte("addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"200\")\n")
+	newDepot := []byte("addappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\naddappid(12,1,\"bb\")\nsetManifestid(12,\"300\")\n")
+	// 只改变了注释, ManifestID 相同
+	reformatted := []byte("-- 10\naddappid(10)\naddappid(11,1,\"aa\")\nsetManifestid(11,\"100\")\n")
+
+	tests := []struct {
+		name        string
+		upstream    []byte
+		cached      []byte // 上次下载时缓存的上游内容, 为 nil 时没有缓存记录
+		wantStatus  string
+		wantChanged int
+	}{
+		{"same content without cache", local, nil, UpdateUnchanged, 0},
+		{"manifest id changed", newManifest, nil, UpdateOutdated, 1},
+		{"depot added", newDepot, nil, UpdateOutdated, 1},
+		{"only text changed without cache", reformatted, nil, UpdateUnchanged, 0},
+		{"same content as cached", reformatted, reformatted, UpdateUnchanged, 0},
+		{"content changed since cached", reformatted, local, UpdateOutdated, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := setupUpdateTest(t, tt.upstream)
+			if err := os.WriteFile(filepath.Join(config.DownloadPath, "10.lua"), local, 0644); err != nil {
+				t.Fatal(err)
+			}
+			var cache *ManifestCache
+			if tt.cached != nil {
+				cache = &ManifestCache{Dir: t.TempDir()}
+				if _, err := cache.Store("10", Sources[0].Name(), "", tt.cached); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := checkUpdate(context.Background(), config, cache, "10", true)
+			if err != nil {
+				t.Fatalf("checkUpdate: %v", err)
+			}
+			if result.Status != tt.wantStatus || result.ManifestsChanged != tt.wantChanged {
+				t.Errorf("checkUpdate = %s, %d changed; want %s, %d", result.Status, result.ManifestsChanged, tt.wantStatus, tt.wantChanged)
+			}
+			if result.NewSHA256 != sha256Hex(tt.upstream) {
+				t.Errorf("NewSHA256 = %s, want hash of upstream", result.NewSHA256)
+			}
+			if cache == nil {
+				return
+			}
+			if result.OldSHA256 != sha256Hex(tt.cached) {
+				t.Errorf("OldSHA256 = %s, want hash of cached content", result.OldSHA256)
+			}
+			// 只检查时恢复缓存记录, 下次仍能发现变化
+			if entry, _ := cache.Entry("10"); tt.wantStatus == UpdateOutdated && (entry == nil || entry.SHA256 != sha256Hex(tt.cached)) {
+				t.Errorf("cache entry after check = %+v, want restored", entry)
+			}
+		})
+	}
+}
+
+func TestCheckUpdateErrors(t *testing.T) {
+	config := setupUpdateTest(t, nil)
+	if _, err := checkUpdate(context.Background(), config, nil, "10", true); err == nil {
+		t.Fatal("checkUpdate succeeded without a downloaded file")
+	}
+	if err := os.WriteFile(filepath.Join(config.DownloadPath, "10.lua"), []byte("addappid(10)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkUpdate(context.Background(), config, nil, "10", true); !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("checkUpdate with upstream 404: err = %v, want ErrManifestNotFound", err)
+	}
+}
+
+func TestScanDownloads(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"730.lua", "10.lua", "notes.lua", "20.lua.part", "1000.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "40.lua"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	appids, err := scanDownloads(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(appids, []string{"10", "730"}) {
+		t.Fatalf("scanDownloads = %v, want [10 730]", appids)
+	}
+}
+
+func TestRunUpdateNDJSON(t *testing.T) {
+	config := setupUpdateTest(t, []byte("addappid(10)\n"))
+	config.Output = OutputNDJSON
+	for _, appid := range []string{"10", "20"} {
+		if err := os.WriteFile(filepath.Join(config.DownloadPath, appid+".lua"), []byte("addappid(10)\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := RunUpdate(context.Background(), config, []string{"--check"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if n := ndjsonLines(t, out.String()); n != 2 {
+		t.Fatalf("RunUpdate wrote %d records, want 2:\n%s", n, out.String())
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,14 +18,14 @@ import (
 // 读取整行输入
 func GetUserInput(prompt string) (string, error) {
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print(prompt)
+	fmt.Fprint(console, prompt)
 	input, err := reader.ReadString('\n')
 	if err != nil {
 		// 如果是 EOF（比如输入被关闭或用户按了 Ctrl+Z），向上返回原始错误，调用方处理
-		if err == io.EOF {
+		if errors.Is(err, io.EOF) {
 			return "", err
 		}
-		return "", fmt.Errorf("读取输入失败: %v", err)
+		return "", Err("input.read_failed", err)
 	}
 	// 去除输入前后的空格和换行符
 	return strings.TrimSpace(input), nil
@@ -33,7 +35,7 @@ func GetUserInput(prompt string) (string, error) {
 func ExtractAppID(userInput string) (int, error) {
 	input := strings.TrimSpace(userInput)
 	if input == "" {
-		return 0, fmt.Errorf("输入不能为空")
+		return 0, markError(ErrInvalidInput, Err("input.empty"))
 	}
 
 	// 匹配链接中的 AppID
@@ -45,122 +47,198 @@ func ExtractAppID(userInput string) (int, error) {
 		appIDStr := matches[1]
 		appID, err := strconv.Atoi(appIDStr)
 		if err != nil {
-			return 0, fmt.Errorf("提取的AppID不是有效数字: %s", appIDStr)
+			return 0, markError(ErrInvalidInput, Err("input.bad_appid", appIDStr))
 		}
-		fmt.Printf("从输入中提取到AppID: %d\n", appID)
+		logger.Info(T("input.extracted", appID), "appid", appID)
 		return appID, nil
 	}
 
 	// 输入本身是纯数字
 	appID, err := strconv.Atoi(input)
 	if err == nil {
-		fmt.Printf("输入为纯数字AppID: %d\n", appID)
+		logger.Info(T("input.numeric", appID), "appid", appID)
 		return appID, nil
 	}
 
 	// 情况3：无效输入
-	return 0, fmt.Errorf("无效格式，请输入含空格的游戏名称、Steam URL或纯数字AppID")
+	return 0, markError(ErrInvalidInput, Err("input.invalid"))
 }
 
 // 按游戏名称搜索AppID
-func FindAppID(gameName string) ([]Game, error) {
+func FindAppID(ctx context.Context, config *Config, gameName string) ([]Game, error) {
 	gameName = strings.TrimSpace(gameName)
 	if gameName == "" {
-		return nil, fmt.Errorf("游戏名称不能为空")
+		return nil, Err("search.empty_name")
+	}
+
+	// 根据搜索模式选择在线搜索或本地索引
+	var games []Game
+	var err error
+	switch config.SearchMode {
+	case SearchModeLocal:
+		games, err = SearchLocal(config, gameName)
+	case SearchModeOnline:
+		games, err = SearchOnlineAPI(ctx, gameName)
+	default:
+		games, err = SearchOnlineAPI(ctx, gameName)
+		if err != nil {
+			logger.Warn(T("search.online_failed", err), "step", "search")
+			games, err = SearchLocal(config, gameName)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 过滤空结果
+	if len(games) == 0 {
+		logger.Info(T("search.no_match", gameName), "step", "search")
+		return nil, nil
 	}
 
+	// 按匹配程度排序并去重
+	games = RankGames(gameName, games)
+	logger.Info(T("search.found", len(games)), "step", "search")
+	return games, nil
+}
+
+// 通过 SteamUI 在线搜索游戏
+func SearchOnlineAPI(ctx context.Context, gameName string) ([]Game, error) {
 	// 处理URL编码（支持空格、特殊字符）
 	encodedName := url.QueryEscape(gameName)
 	apiURL := fmt.Sprintf("https://steamui.com/api/loadGames.php?search=%s", encodedName)
-	fmt.Printf("正在搜索游戏: %s (请求URL: %s)\n", gameName, apiURL)
+	logger.Info(T("search.searching", gameName), "step", "search")
+	logger.Debug(T("search.request_url", apiURL), "source", apiURL, "step", "search")
 
 	// 发送请求
-	resp, err := httpClient.Get(apiURL)
+	resp, err := fetch(ctx, httpClient, apiURL, 0, maxJSONSize)
 	if err != nil {
-		return nil, fmt.Errorf("搜索请求失败: %v", err)
+		return nil, Err("search.request_failed", err)
 	}
-	defer resp.Body.Close()
 
 	// 检查状态码
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("搜索API返回错误状态码: %d", resp.StatusCode)
+	if resp.Status != http.StatusOK {
+		return nil, Err("search.bad_status", resp.Status)
 	}
 
 	// 解析 JSON
 	var response LoadGamesResponse
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&response); err != nil {
-		return nil, fmt.Errorf("解析搜索结果失败: %v（可能API返回格式变更）", err)
-	}
-
-	// 过滤空结果
-	if len(response.Games) == 0 {
-		fmt.Printf("未找到与 '%s' 匹配的游戏\n", gameName)
-		return nil, nil
-	}
-
-	// 输出搜索结果
-	fmt.Printf("找到 %d 个匹配的游戏:\n", len(response.Games))
-	for i, game := range response.Games {
-		// 格式化输出，对齐显示
-		fmt.Printf(" %d. %-30s | AppID: %d\n",
-			i+1, game.Name, game.AppID)
+	if err := json.Unmarshal(resp.Body, &response); err != nil {
+		return nil, Err("search.parse_failed", err)
 	}
 
 	return response.Games, nil
 }
 
-// AppID 选择
-func GetAppID() (int, error) {
-	fmt.Println(Division)
+// AppID 选择, 输入为本地 ZIP/Lua 文件时返回文件路径
+// 按名称搜索及显示结果时 Ctrl+C 只取消本次搜索
+func GetAppID(config *Config) (int, string, error) {
+	printDivision()
 	// 读取整行输入
-	input, err := GetUserInput("请输入游戏名称/AppID/Steam链接/SteamDB链接:")
+	input, err := GetUserInput(T("input.prompt"))
 	if err != nil {
 		// 将 EOF 原样传递，调用方可选择退出
-		if err == io.EOF {
-			return 0, io.EOF
+		if errors.Is(err, io.EOF) {
+			return 0, "", io.EOF
 		}
-		return 0, fmt.Errorf("读取输入失败: %v", err)
+		return 0, "", Err("input.read_failed", err)
+	}
+
+	// 本地 ZIP/Lua 文件
+	if path, ok := LocalPath(input); ok {
+		return 0, path, nil
 	}
 
 	// 优先尝试提取AppID
 	appID, err := ExtractAppID(input)
 	if err == nil {
-		return appID, nil
+		return appID, "", nil
 	}
 
 	// 提取失败，尝试按名称搜索
-	fmt.Printf("无法直接提取AppID，将尝试按名称 '%s' 搜索...\n", input)
-	games, err := FindAppID(input)
+	logger.Info(T("input.fallback_search", input), "step", "search")
+	var games []Game
+	shown := 0
+	err = runInteractive(func(ctx context.Context) error {
+		games, err = FindAppID(ctx, config, input)
+		if err != nil {
+			return Err("search.failed", err)
+		}
+
+		// 无搜索结果
+		if len(games) == 0 {
+			return Err("search.none")
+		}
+
+		// 分页显示结果并让用户选择游戏
+		shown = PrintGames(ctx, config, games, 0, config.SearchLimit)
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("搜索游戏失败: %v", err)
+		return 0, "", err
 	}
+	var selection int
+	for {
+		prompt := T("input.select")
+		if shown < len(games) {
+			prompt = T("input.select_more", len(games)-shown)
+		}
+		selectionStr, err := GetUserInput(prompt)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, "", io.EOF
+			}
+			return 0, "", Err("input.select_read_failed", err)
+		}
 
-	// 无搜索结果
-	if len(games) == 0 {
-		return 0, fmt.Errorf("未找到任何匹配的游戏，请重新输入")
-	}
+		// 显示下一页
+		if strings.EqualFold(selectionStr, "m") && shown < len(games) {
+			err := runInteractive(func(ctx context.Context) error {
+				shown = PrintGames(ctx, config, games, shown, config.SearchLimit)
+				return nil
+			})
+			if err != nil {
+				return 0, "", err
+			}
+			continue
+		}
 
-	// 让用户选择游戏
-	selectionStr, err := GetUserInput("请输入需要下载的游戏序号:")
-	if err != nil {
-		if err == io.EOF {
-			return 0, io.EOF
+		selection, err = strconv.Atoi(selectionStr)
+		if err != nil {
+			return 0, "", Err("input.select_nan", shown)
 		}
-		return 0, fmt.Errorf("读取选择失败: %v", err)
-	}
-	selection, err := strconv.Atoi(selectionStr)
-	if err != nil {
-		return 0, fmt.Errorf("序号必须是数字，请输入 1-%d 之间的序号", len(games))
+		break
 	}
 
 	// 验证序号合法性
-	if selection < 1 || selection > len(games) {
-		return 0, fmt.Errorf("无效序号，请选择 1-%d 之间的数字", len(games))
+	if selection < 1 || selection > shown {
+		return 0, "", Err("input.select_range", shown)
 	}
 
 	// 返回选中的AppID
 	targetGame := games[selection-1]
-	fmt.Printf("已选择游戏: %s (AppID: %d)\n", targetGame.Name, targetGame.AppID)
-	return targetGame.AppID, nil
+	// 可用性只作提示, 检查失败或下载源暂时不可用时仍然尝试下载
+	switch targetGame.Availability {
+	case AvailabilityMissing:
+		logger.Warn(T("input.select_unavailable", targetGame.Name, targetGame.AppID), "appid", targetGame.AppID)
+	case AvailabilityUnknown:
+		logger.Warn(T("input.select_unknown", targetGame.Name, targetGame.AppID), "appid", targetGame.AppID)
+	}
+	logger.Info(T("input.selected", targetGame.Name, targetGame.AppID), "appid", targetGame.AppID)
+	return targetGame.AppID, "", nil
+}
+
+// 在交互模式的单次操作上下文中执行 fn
+// 收到 Ctrl+C 时返回 context.Canceled 分类的错误, 收到 SIGTERM 时返回 errTerminated
+func runInteractive(fn func(ctx context.Context) error) error {
+	ctx, stop := interactiveContext()
+	err := fn(ctx)
+	canceled := ctx.Err() != nil
+	if stop() {
+		return errTerminated
+	}
+	if canceled {
+		return markError(context.Canceled, Err("cancel.search_canceled"))
+	}
+	return err
 }
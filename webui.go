@@ -0,0 +1,34 @@
+package main
+
+import (
+	"embed"
+	"net/http"
+)
+
+// 网页界面, 编译时嵌入程序
+//
+//go:embed web/index.html
+var webFiles embed.FS
+
+// 注册网页界面
+func (s *apiServer) uiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /ui/messages", s.handleUIMessages)
+}
+
+// GET /: 网页界面
+func (s *apiServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	page, err := webFiles.ReadFile("web/index.html")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(page)
+}
+
+// GET /ui/messages: 网页界面使用的当前语言的文字
+func (s *apiServer) handleUIMessages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"lang": language, "messages": Messages("web.")})
+}
@@ -0,0 +1,617 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ZIP 源的重试次数与空闲超时
+var (
+	zipMaxRetries         = 2                 // 读取中断或长时间无进展时最多重试的次数
+	zipMinIdleTimeout     = 60 * time.Second  // 已知文件大小时的最小空闲超时
+	zipDefaultIdleTimeout = 120 * time.Second // 未知文件大小时的空闲超时
+)
+
+// ZIP 文件的大小限制, 各项为 0 时不限制
+type ZipLimitOptions struct {
+	MaxArchiveSize int64   // 压缩包大小上限
+	MaxEntrySize   int64   // 单个文件解压后的大小上限
+	MaxTotalSize   int64   // 所有文件解压后的总大小上限
+	MaxEntries     int     // 文件数上限
+	MaxRatio       float64 // 单个文件的最大压缩比(解压后/压缩后), 超过时视为压缩炸弹
+}
+
+// 当前的 ZIP 大小限制, 可在配置文件中修改
+var ZipLimits = ZipLimitOptions{
+	MaxArchiveSize: 512 << 20,
+	MaxEntrySize:   64 << 20,
+	MaxTotalSize:   1 << 30,
+	MaxEntries:     10000,
+	MaxRatio:       100,
+}
+
+// 解压后小于此大小的文件不检查压缩比, 重复内容多的小清单压缩比可能很高
+const zipRatioMinSize = 1 << 20
+
+// 未完成的 ZIP 下载保存的目录及保留时间, 超过保留时间的部分文件会被清理
+var (
+	zipPartDir    = filepath.Join(os.TempDir(), "manifesthub")
+	zipPartMaxAge = 24 * time.Hour
+)
+
+// 下载过程中长时间没有读到数据
+var errIdleTimeout = error(&sentinelError{"zip.idle_timeout"})
+
+// 尝试从zip源下载, 返回 <AppID>.lua 的内容及下载的压缩包路径
+// 成功时压缩包由调用方在使用完后删除
+func tryZipSource(ctx context.Context, zipURL, APPID string) ([]byte, string, error) {
+	cleanStalePartials(zipPartDir, zipPartMaxAge)
+
+	zipPath, err := downloadZip(ctx, zipURL, APPID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := extractLua(zipPath, APPID, zipURL)
+	if err != nil {
+		os.Remove(zipPath)
+		return nil, "", err
+	}
+	return data, zipPath, nil
+}
+
+// ZIP 文件的 HEAD 信息
+type zipHead struct {
+	Size         int64  // Content-Length, 未知时为 0
+	AcceptRanges bool   // 是否支持 Range 请求
+	Validator    string // 用于 If-Range 的 ETag 或 Last-Modified
+}
+
+// 下载 ZIP 文件到临时文件并返回路径, 读取中断或长时间无进展时重试
+// 服务器支持 Range 时从已下载的位置继续, 否则从头下载
+// 返回的路径对本次下载唯一, 由调用方在使用完后删除
+func downloadZip(ctx context.Context, zipURL, APPID string) (string, error) {
+	if err := os.MkdirAll(zipPartDir, 0755); err != nil {
+		return "", Err("fs.mkdir_failed", err)
+	}
+	partPath, shared, release, err := acquirePartFile(APPID)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	// 先尝试 HEAD 获取文件大小及是否支持续传
+	head := headZip(ctx, zipURL)
+
+	var lastErr error
+	for retry := 0; retry <= zipMaxRetries; retry++ {
+		if retry > 0 {
+			logger.Info(T("zip.retry", retry), "appid", APPID, "source", zipURL, "step", "fetch")
+		}
+
+		retryable, err := downloadZipOnce(ctx, zipURL, APPID, partPath, head)
+		if err == nil {
+			return finishPartFile(partPath, APPID)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// 保留共享的部分文件, 下次可以继续; 独立的部分文件无法续传, 直接删除
+			if !shared {
+				os.Remove(partPath)
+			}
+			return "", Err("cancel.canceled", ctxErr)
+		}
+		if !retryable {
+			os.Remove(partPath)
+			return "", err
+		}
+		if retry < zipMaxRetries {
+			logger.Warn(T("zip.will_retry", err), "appid", APPID, "source", zipURL, "step", "fetch")
+		}
+		lastErr = err
+	}
+	if !shared {
+		os.Remove(partPath)
+	}
+	return "", lastErr
+}
+
+// 获取 AppID 的部分文件, 返回路径、是否为可续传的共享文件及释放函数
+// 共享文件 <AppID>.zip.part 通过同名的 .lock 文件独占使用; 其他进程(或同一进程中的其他下载)
+// 正在使用时改用一个独立的新文件, 本次下载不续传, 也不会影响对方的文件
+func acquirePartFile(APPID string) (string, bool, func(), error) {
+	partPath := filepath.Join(zipPartDir, APPID+".zip.part")
+	lockPath := partPath + ".lock"
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err == nil {
+		lock.Close()
+		return partPath, true, func() { os.Remove(lockPath) }, nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return "", false, nil, Err("fs.open_failed", err)
+	}
+
+	logVerbose(T("zip.part_in_use", partPath), "appid", APPID, "step", "fetch")
+	file, err := os.CreateTemp(zipPartDir, APPID+"-*.zip.part")
+	if err != nil {
+		return "", false, nil, Err("fs.open_failed", err)
+	}
+	file.Close()
+	return file.Name(), false, func() {}, nil
+}
+
+// 把下载完成的部分文件重命名为本次下载独有的路径, 释放共享文件后其他下载不会改动它
+func finishPartFile(partPath, APPID string) (string, error) {
+	file, err := os.CreateTemp(zipPartDir, APPID+"-*.zip")
+	if err != nil {
+		os.Remove(partPath)
+		return "", Err("fs.open_failed", err)
+	}
+	file.Close()
+	if err := os.Rename(partPath, file.Name()); err != nil {
+		os.Remove(partPath)
+		os.Remove(file.Name())
+		return "", Err("fs.save_failed", err)
+	}
+	return file.Name(), nil
+}
+
+// 下载一次 ZIP 文件到 partPath, 返回失败时是否值得重试以及错误
+func downloadZipOnce(ctx context.Context, zipURL, APPID, partPath string, head zipHead) (bool, error) {
+	idleTimeout := zipIdleTimeout(head.Size)
+	maxSize := ZipLimits.MaxArchiveSize
+	if maxSize > 0 && head.Size > maxSize {
+		return false, markError(ErrInvalidZip, Err("zip.archive_too_large", head.Size, maxSize))
+	}
+
+	// 不支持续传, 或已下载的部分不小于文件大小(文件已变化或上次未能校验)时从头下载
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+	if !head.AcceptRanges || (head.Size > 0 && offset >= head.Size) {
+		offset = 0
+	}
+
+	// 空闲超时到期时取消本次请求(包括等待响应头的时间)
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watchdog := newIdleWatchdog(idleTimeout, cancel)
+	defer watchdog.Stop()
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", zipURL, nil)
+	if err != nil {
+		return false, Err("http.new_request_failed", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if head.Validator != "" {
+			// 文件已变化时服务器返回完整内容
+			req.Header.Set("If-Range", head.Validator)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if watchdog.Expired() {
+			logger.Warn(T("zip.idle", idleTimeout), "appid", APPID, "source", zipURL, "step", "fetch")
+			return true, markError(ErrNetwork, Err("http.request_failed", errIdleTimeout))
+		}
+		return false, markError(ErrNetwork, Err("http.request_failed", err))
+	}
+	defer resp.Body.Close()
+
+	// 根据状态码决定续传还是从头写入
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+		logger.Info(T("zip.resuming", offset), "appid", APPID, "source", zipURL, "step", "fetch")
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// 已下载的部分无法续传, 丢弃后重试
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+		os.Remove(partPath)
+		return true, Err("zip.bad_status", resp.StatusCode, zipURL)
+	default:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+		return false, statusError(Err("zip.bad_status", resp.StatusCode, zipURL), resp.StatusCode)
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return false, Err("fs.open_failed", err)
+	}
+
+	// 边读取边显示进度, 读取中断时已写入的部分保留在文件中
+	// 超过大小上限时多读的一个字节用于判断
+	body := watchdog.Reader(resp.Body)
+	if maxSize > 0 {
+		body = io.LimitReader(body, maxSize-offset+1)
+	}
+	progress := &zipProgress{total: head.Size, downloaded: offset, start: time.Now()}
+	_, copyErr := io.Copy(io.MultiWriter(file, progress), body)
+	printProgress("\n")
+	if err := file.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		if errors.Is(copyErr, errIdleTimeout) {
+			logger.Warn(T("zip.idle", idleTimeout), "appid", APPID, "source", zipURL, "step", "fetch")
+		}
+		return true, markError(ErrNetwork, Err("zip.read_error", copyErr))
+	}
+
+	// 校验文件大小
+	info, err := os.Stat(partPath)
+	if err != nil {
+		return false, Err("fs.read_failed", err)
+	}
+	if info.Size() == 0 {
+		// 没有读取到数据时重试
+		return true, markError(ErrInvalidZip, Err("zip.empty", zipURL))
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		os.Remove(partPath)
+		return false, markError(ErrInvalidZip, Err("zip.archive_too_large", info.Size(), maxSize))
+	}
+	if head.Size > 0 && info.Size() != head.Size {
+		os.Remove(partPath)
+		return true, markError(ErrInvalidZip, Err("zip.size_mismatch", info.Size(), head.Size))
+	}
+	return false, nil
+}
+
+// 发送 HEAD 请求获取文件大小及是否支持续传, 失败时返回零值
+func headZip(ctx context.Context, url string) zipHead {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var head zipHead
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return head
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return head
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return head
+	}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && size > 0 {
+		head.Size = size
+	}
+	head.AcceptRanges = strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes")
+
+	// 弱 ETag 不能用于 If-Range
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		head.Validator = etag
+	} else {
+		head.Validator = resp.Header.Get("Last-Modified")
+	}
+	return head
+}
+
+// 清理超过保留时间的未完成下载, 以及异常退出的进程遗留的锁和压缩包
+func cleanStalePartials(dir string, maxAge time.Duration) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".zip.part") || strings.HasSuffix(name, ".zip.part.lock") || strings.HasSuffix(name, ".zip")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+		partPath := filepath.Join(dir, name)
+		if err := os.Remove(partPath); err == nil {
+			logVerbose(T("zip.stale_removed", partPath), "step", "fetch")
+		}
+	}
+}
+
+// 根据文件大小计算空闲超时
+// 假设最低持续速度 8KB/s, 空闲超时 = 文件大小/8KB + 最小空闲超时; 未知大小时使用默认值
+func zipIdleTimeout(contentLen int64) time.Duration {
+	if contentLen <= 0 {
+		return zipDefaultIdleTimeout
+	}
+	return time.Duration(contentLen/(8*1024))*time.Second + zipMinIdleTimeout
+}
+
+// 空闲超时监测: 超过 timeout 没有进展时调用 cancel 取消请求
+// 计时器由 time.AfterFunc 管理, 读取方只通过 Reset 与原子标志交互, 不存在数据竞争
+type idleWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+func newIdleWatchdog(timeout time.Duration, cancel context.CancelFunc) *idleWatchdog {
+	w := &idleWatchdog{timeout: timeout}
+	w.timer = time.AfterFunc(timeout, func() {
+		w.expired.Store(true)
+		cancel()
+	})
+	return w
+}
+
+// 是否已因空闲超时取消
+func (w *idleWatchdog) Expired() bool { return w.expired.Load() }
+
+// 停止计时
+func (w *idleWatchdog) Stop() { w.timer.Stop() }
+
+// 包装读取器, 每次读到数据都重新计时
+func (w *idleWatchdog) Reader(r io.Reader) io.Reader {
+	return &idleReader{r: r, watchdog: w}
+}
+
+// 读到数据时重置空闲计时的读取器
+type idleReader struct {
+	r        io.Reader
+	watchdog *idleWatchdog
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.watchdog.Expired() {
+		// 读取失败是由空闲超时取消引起的
+		return n, errIdleTimeout
+	}
+	if n > 0 && !r.watchdog.Expired() {
+		r.watchdog.timer.Reset(r.watchdog.timeout)
+	}
+	return n, err
+}
+
+// ZIP 下载进度
+type zipProgress struct {
+	total      int64     // 文件大小, 未知时为 0
+	downloaded int64     // 已下载字节数
+	start      time.Time // 开始时间
+}
+
+func (p *zipProgress) Write(b []byte) (int, error) {
+	p.downloaded += int64(len(b))
+
+	// 计算并打印进度/速度(KB/s)
+	elapsed := time.Since(p.start)
+	if elapsed <= 0 {
+		elapsed = time.Millisecond
+	}
+	speedKB := float64(p.downloaded) / 1024.0 / elapsed.Seconds()
+	if p.total > 0 {
+		pct := float64(p.downloaded) / float64(p.total) * 100.0
+		printProgress(T("zip.progress_pct"), pct, p.downloaded, p.total, speedKB)
+	} else {
+		printProgress(T("zip.progress"), p.downloaded, speedKB)
+	}
+	return len(b), nil
+}
+
+// 从 ZIP 文件中提取 <AppID>.lua, 解压前检查大小、压缩比与文件名
+func extractLua(zipPath, APPID, zipURL string) ([]byte, error) {
+	expectedFileName := APPID + ".lua"
+
+	// 简单校验 ZIP magic header(以避免解析 HTML/错误页面)
+	if !hasZipHeader(zipPath) {
+		return nil, markError(ErrInvalidZip, Err("zip.not_zip", zipURL))
+	}
+
+	// 解析ZIP
+	zipReader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, markError(ErrInvalidZip, Err("zip.parse_failed", err))
+	}
+	defer zipReader.Close()
+
+	if err := checkZipArchive(zipReader.File, ZipLimits); err != nil {
+		return nil, markError(ErrInvalidZip, err)
+	}
+
+	// 查找目标文件(支持嵌套目录, 只匹配文件名)
+	for _, file := range zipReader.File {
+		// 只比较文件名(如"subdir/123.lua"也能匹配"123.lua")
+		if zipBaseName(file.Name) != expectedFileName || file.FileInfo().IsDir() {
+			continue
+		}
+		data, err := readZipEntry(file, ZipLimits.MaxEntrySize)
+		if err != nil {
+			return nil, markError(ErrInvalidZip, err)
+		}
+
+		logger.Info(T("zip.extracted", expectedFileName, len(data)), "appid", APPID, "source", zipURL, "step", "fetch")
+		printDivision()
+		return data, nil
+	}
+
+	// 没找到文件, 无需重试(内容问题, 重试也没用)
+	return nil, markError(ErrManifestNotFound, Err("zip.not_found", expectedFileName, zipURL))
+}
+
+// 把压缩包中除 <AppID>.lua 以外的文件解压到 destDir, 保留目录结构, 返回解压的文件(相对路径)
+// <AppID>.lua 由常规流程处理后保存到下载路径
+func ExtractArchive(ctx context.Context, zipPath, destDir, APPID string) ([]string, error) {
+	zipReader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, markError(ErrInvalidZip, Err("zip.parse_failed", err))
+	}
+	defer zipReader.Close()
+
+	if err := checkZipArchive(zipReader.File, ZipLimits); err != nil {
+		return nil, markError(ErrInvalidZip, err)
+	}
+
+	var extracted []string
+	for _, file := range zipReader.File {
+		if err := ctx.Err(); err != nil {
+			return extracted, Err("cancel.canceled", err)
+		}
+		if file.FileInfo().IsDir() || zipBaseName(file.Name) == APPID+".lua" {
+			continue
+		}
+
+		name := filepath.FromSlash(strings.ReplaceAll(file.Name, "\\", "/"))
+		target := filepath.Join(destDir, name)
+		// 再次确认目标路径位于解压目录内
+		if rel, err := filepath.Rel(destDir, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return extracted, markError(ErrInvalidZip, Err("zip.unsafe_name", file.Name))
+		}
+
+		size, err := extractZipEntry(file, target, ZipLimits.MaxEntrySize)
+		if err != nil {
+			return extracted, err
+		}
+		rel := filepath.ToSlash(name)
+		extracted = append(extracted, rel)
+		logger.Info(T("zip.extracted_file", rel, size), "appid", APPID, "step", "extract")
+	}
+	return extracted, nil
+}
+
+// 把压缩包中的单个文件写入 target, 超过 limit 字节时删除已写入的部分并返回错误
+func extractZipEntry(file *zip.File, target string, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return 0, Err("fs.mkdir_failed", err)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return 0, markError(ErrInvalidZip, Err("zip.open_entry_failed", err))
+	}
+	defer rc.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return 0, Err("fs.open_failed", err)
+	}
+
+	reader := io.Reader(rc)
+	if limit > 0 {
+		reader = io.LimitReader(rc, limit+1)
+	}
+	size, err := io.Copy(out, reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err != nil:
+		os.Remove(target)
+		return 0, markError(ErrInvalidZip, Err("zip.read_entry_failed", err))
+	case limit > 0 && size > limit:
+		os.Remove(target)
+		return 0, markError(ErrInvalidZip, Err("zip.entry_too_large", file.Name, size, limit))
+	}
+	return size, nil
+}
+
+// 检查文件开头是否为 ZIP 的 magic header
+func hasZipHeader(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	return bytes.HasPrefix(header, []byte("PK"))
+}
+
+// 检查压缩包的文件数、文件名、解压后大小与压缩比
+// 依据的是目录中声明的大小, 实际读取时还会按上限截断
+func checkZipArchive(files []*zip.File, limits ZipLimitOptions) error {
+	if limits.MaxEntries > 0 && len(files) > limits.MaxEntries {
+		return Err("zip.too_many_entries", len(files), limits.MaxEntries)
+	}
+
+	var total uint64
+	for _, file := range files {
+		if !safeZipName(file.Name) {
+			return Err("zip.unsafe_name", file.Name)
+		}
+		size := file.UncompressedSize64
+		if limits.MaxEntrySize > 0 && size > uint64(limits.MaxEntrySize) {
+			return Err("zip.entry_too_large", file.Name, size, limits.MaxEntrySize)
+		}
+		if limits.MaxRatio > 0 && size >= zipRatioMinSize {
+			// 压缩后大小为 0 的条目同样视为异常
+			if file.CompressedSize64 == 0 || float64(size)/float64(file.CompressedSize64) > limits.MaxRatio {
+				return Err("zip.ratio_too_high", file.Name, limits.MaxRatio)
+			}
+		}
+		total += size
+		if limits.MaxTotalSize > 0 && total > uint64(limits.MaxTotalSize) {
+			return Err("zip.total_too_large", limits.MaxTotalSize)
+		}
+	}
+	return nil
+}
+
+// 读取压缩包中的文件, 超过 limit 字节时返回错误
+func readZipEntry(file *zip.File, limit int64) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, Err("zip.open_entry_failed", err)
+	}
+	defer rc.Close()
+
+	reader := io.Reader(rc)
+	if limit > 0 {
+		reader = io.LimitReader(rc, limit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, Err("zip.read_entry_failed", err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, Err("zip.entry_too_large", file.Name, len(data), limit)
+	}
+	return data, nil
+}
+
+// 判断压缩包中的文件名是否安全: 不能是绝对路径, 不能包含 ".." 路径段
+func safeZipName(name string) bool {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return false
+	}
+	// Windows 盘符(如 C:)在其他系统上不会被识别为绝对路径
+	if len(name) >= 2 && name[1] == ':' {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// 压缩包内文件的文件名部分(同时支持 / 和 \ 分隔)
+func zipBaseName(name string) string {
+	return path.Base(strings.ReplaceAll(name, "\\", "/"))
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试时关闭日志与进度输出, 并缩短超时
+func setupZipTest(t *testing.T, idleTimeout time.Duration, retries int) {
+	t.Helper()
+	oldLogger, oldLevel := logger, consoleLevel
+	oldRetries, oldMin, oldDefault := zipMaxRetries, zipMinIdleTimeout, zipDefaultIdleTimeout
+	oldPartDir := zipPartDir
+	zipPartDir = t.TempDir()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	consoleLevel = LevelWarn
+	zipMaxRetries = retries
+	zipMinIdleTimeout = idleTimeout
+	zipDefaultIdleTimeout = idleTimeout
+	t.Cleanup(func() {
+		logger, consoleLevel = oldLogger, oldLevel
+		zipMaxRetries, zipMinIdleTimeout, zipDefaultIdleTimeout = oldRetries, oldMin, oldDefault
+		zipPartDir = oldPartDir
+	})
+}
+
+// 构造包含 <appid>.lua 的 ZIP
+func makeZip(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// 下载 ZIP 并返回内容
+func downloadZipData(t *testing.T, url string) []byte {
+	t.Helper()
+	path, err := downloadZip(context.Background(), url, "123")
+	if err != nil {
+		t.Fatalf("downloadZip: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// 分块发送数据, 每块之间等待 delay
+func writeSlowly(w http.ResponseWriter, r *http.Request, data []byte, chunk int, delay time.Duration) {
+	flusher := w.(http.Flusher)
+	for len(data) > 0 {
+		n := min(chunk, len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			return
+		}
+		flusher.Flush()
+		data = data[n:]
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func TestDownloadZipSlowButSteady(t *testing.T) {
+	setupZipTest(t, 200*time.Millisecond, 0)
+	content := bytes.Repeat([]byte("addappid(1)\n"), 200)
+	data := makeZip(t, "nested/123.lua", content)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// 总耗时远超空闲超时, 但每块之间的间隔小于空闲超时
+		writeSlowly(w, r, data, 64, 20*time.Millisecond)
+	}))
+	defer server.Close()
+
+	path, err := downloadZip(context.Background(), server.URL, "123")
+	if err != nil {
+		t.Fatalf("downloadZip: %v", err)
+	}
+	lua, err := extractLua(path, "123", server.URL)
+	if err != nil {
+		t.Fatalf("extractLua: %v", err)
+	}
+	if !bytes.Equal(lua, content) {
+		t.Fatalf("extracted %d bytes, want %d", len(lua), len(content))
+	}
+}
+
+func TestDownloadZipIdleTimeout(t *testing.T) {
+	setupZipTest(t, 100*time.Millisecond, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// 发送部分数据后停止, 直到客户端断开
+		w.Write([]byte("PK"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	_, err := downloadZip(context.Background(), server.URL, "123")
+	if !errors.Is(err, errIdleTimeout) {
+		t.Fatalf("err = %v, want idle timeout", err)
+	}
+	if !errors.Is(err, ErrNetwork) {
+		t.Fatalf("err = %v, want network error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("idle timeout took %v", elapsed)
+	}
+}
+
+func TestDownloadZipRetriesAfterStall(t *testing.T) {
+	setupZipTest(t, 100*time.Millisecond, 2)
+	content := []byte("addappid(123)\n")
+	data := makeZip(t, "123.lua", content)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// 第一次请求不发送响应头, 之后正常返回
+		if requests.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	if got := downloadZipData(t, server.URL); !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(data))
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("requests = %d, want 2", n)
+	}
+}
+
+func TestDownloadZipResumesWithRange(t *testing.T) {
+	setupZipTest(t, 100*time.Millisecond, 2)
+	data := makeZip(t, "123.lua", bytes.Repeat([]byte("addappid(123)\n"), 500))
+	modTime := time.Now()
+
+	var requests atomic.Int32
+	var resumedFrom atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			// 第一次请求只发送一半后停止, 之后按 Range 返回剩余部分
+			if requests.Add(1) == 1 {
+				w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+				w.Write(data[:len(data)/2])
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			resumedFrom.Store(r.Header.Get("Range"))
+		}
+		http.ServeContent(w, r, "123.zip", modTime, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	if got := downloadZipData(t, server.URL); !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(data))
+	}
+	if got, _ := resumedFrom.Load().(string); got != "bytes="+strconv.Itoa(len(data)/2)+"-" {
+		t.Fatalf("Range = %q, want resume from %d", got, len(data)/2)
+	}
+}
+
+func TestCleanStalePartials(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "1.zip.part")
+	staleLock := filepath.Join(dir, "1.zip.part.lock")
+	staleZip := filepath.Join(dir, "1-123.zip")
+	fresh := filepath.Join(dir, "2.zip.part")
+	other := filepath.Join(dir, "3.txt")
+	for _, path := range []string{stale, staleLock, staleZip, fresh, other} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{stale, staleLock, staleZip, other} {
+		os.Chtimes(path, old, old)
+	}
+
+	cleanStalePartials(dir, 24*time.Hour)
+
+	for path, want := range map[string]bool{stale: false, staleLock: false, staleZip: false, fresh: true, other: true} {
+		_, err := os.Stat(path)
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", strings.TrimPrefix(path, dir), exists, want)
+		}
+	}
+}
+
+// 同一 AppID 同时下载时各自得到完整且独立的文件
+func TestDownloadZipConcurrentSameApp(t *testing.T) {
+	setupZipTest(t, 5*time.Second, 2)
+	data := makeZip(t, "123.lua", bytes.Repeat([]byte("addappid(123)\n"), 500))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			http.ServeContent(w, r, "123.zip", time.Time{}, bytes.NewReader(data))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		writeSlowly(w, r, data, 512, time.Millisecond)
+	}))
+	defer server.Close()
+
+	const downloads = 4
+	paths := make([]string, downloads)
+	errs := make([]error, downloads)
+	var wg sync.WaitGroup
+	for i := range downloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paths[i], errs[i] = downloadZip(context.Background(), server.URL, "123")
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for i := range downloads {
+		if errs[i] != nil {
+			t.Fatalf("download %d: %v", i, errs[i])
+		}
+		if seen[paths[i]] {
+			t.Fatalf("path %s returned twice", paths[i])
+		}
+		seen[paths[i]] = true
+		if got, err := os.ReadFile(paths[i]); err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("download %d: got %d bytes (%v), want %d", i, len(got), err, len(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(zipPartDir, "123.zip.part.lock")); !os.IsNotExist(err) {
+		t.Fatalf("lock not released: %v", err)
+	}
+}
+
+// 共享的部分文件被占用时使用独立文件, 不改动对方的文件
+func TestDownloadZipPartInUse(t *testing.T) {
+	setupZipTest(t, 5*time.Second, 2)
+	data := makeZip(t, "123.lua", []byte("addappid(123)\n"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "123.zip", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	partPath := filepath.Join(zipPartDir, "123.zip.part")
+	if err := os.WriteFile(partPath, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(partPath+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := downloadZipData(t, server.URL); !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(data))
+	}
+	if got, err := os.ReadFile(partPath); err != nil || string(got) != "other" {
+		t.Fatalf("shared part file changed: %q, %v", got, err)
+	}
+	if _, err := os.Stat(partPath + ".lock"); err != nil {
+		t.Fatalf("lock of the other download removed: %v", err)
+	}
+}
+
+func TestDownloadZipCanceled(t *testing.T) {
+	setupZipTest(t, 5*time.Second, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeSlowly(w, r, bytes.Repeat([]byte("x"), 1<<20), 16, 10*time.Millisecond)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := downloadZip(ctx, server.URL, "123")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context deadline exceeded", err)
+	}
+}
+
+func TestDownloadZipNotFound(t *testing.T) {
+	setupZipTest(t, time.Second, 2)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests.Add(1)
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, err := downloadZip(context.Background(), server.URL, "123")
+	if !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("err = %v, want manifest not found", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("requests = %d, want no retries", n)
+	}
+}
+
+// 把 ZIP 写入临时文件并返回路径
+func writeZipFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.zip")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 构造包含多个文件的 ZIP, 使用 Deflate 压缩
+func makeZipEntries(t *testing.T, entries map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractLuaMissingEntry(t *testing.T) {
+	setupZipTest(t, time.Second, 0)
+	path := writeZipFile(t, makeZip(t, "456.lua", []byte("addappid(456)\n")))
+
+	if _, err := extractLua(path, "123", "test"); !errors.Is(err, ErrManifestNotFound) {
+		t.Fatalf("err = %v, want manifest not found", err)
+	}
+	if _, err := extractLua(writeZipFile(t, []byte("<html>")), "123", "test"); !errors.Is(err, ErrInvalidZip) {
+		t.Fatalf("err = %v, want invalid zip", err)
+	}
+}
+
+func TestExtractLuaLimits(t *testing.T) {
+	setupZipTest(t, time.Second, 0)
+	oldLimits := ZipLimits
+	t.Cleanup(func() { ZipLimits = oldLimits })
+	lua := []byte("addappid(123)\n")
+
+	tests := []struct {
+		name    string
+		entries map[string][]byte
+		limits  ZipLimitOptions
+		wantErr bool
+	}{
+		{"nested match", map[string][]byte{"a/b/123.lua": lua}, oldLimits, false},
+		{"path traversal", map[string][]byte{"../123.lua": lua}, oldLimits, true},
+		{"absolute path", map[string][]byte{"/tmp/123.lua": lua}, oldLimits, true},
+		{"backslash traversal", map[string][]byte{"a\\..\\..\\123.lua": lua}, oldLimits, true},
+		{"drive letter", map[string][]byte{"C:/123.lua": lua}, oldLimits, true},
+		{"zip bomb ratio", map[string][]byte{"123.lua": lua, "pad.bin": make([]byte, 1<<20)}, oldLimits, true},
+		{"small entry ratio ignored", map[string][]byte{"123.lua": lua, "pad.bin": make([]byte, zipRatioMinSize-1)}, oldLimits, false},
+		{"repetitive manifest", map[string][]byte{"123.lua": lua, "1.manifest": bytes.Repeat(lua, 500)}, oldLimits, false},
+		{"too many entries", map[string][]byte{"123.lua": lua, "1.manifest": lua, "2.manifest": lua},
+			ZipLimitOptions{MaxEntries: 2}, true},
+		{"entry too large", map[string][]byte{"123.lua": lua}, ZipLimitOptions{MaxEntrySize: 4}, true},
+		{"total too large", map[string][]byte{"123.lua": lua, "1.manifest": lua},
+			ZipLimitOptions{MaxTotalSize: int64(len(lua)) + 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ZipLimits = tt.limits
+			path := writeZipFile(t, makeZipEntries(t, tt.entries))
+			data, err := extractLua(path, "123", "test")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidZip) {
+					t.Fatalf("err = %v, want invalid zip", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractLua: %v", err)
+			}
+			if !bytes.Equal(data, lua) {
+				t.Fatalf("got %q, want %q", data, lua)
+			}
+		})
+	}
+}
+
+func TestDownloadZipArchiveTooLarge(t *testing.T) {
+	setupZipTest(t, time.Second, 2)
+	oldLimits := ZipLimits
+	ZipLimits.MaxArchiveSize = 1024
+	t.Cleanup(func() { ZipLimits = oldLimits })
+
+	// 不发送 Content-Length, 只能在下载过程中发现超过上限
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeSlowly(w, r, bytes.Repeat([]byte("x"), 4096), 512, time.Millisecond)
+	}))
+	defer server.Close()
+
+	if _, err := downloadZip(context.Background(), server.URL, "123"); !errors.Is(err, ErrInvalidZip) {
+		t.Fatalf("err = %v, want invalid zip", err)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	setupZipTest(t, time.Second, 0)
+	path := writeZipFile(t, makeZipEntries(t, map[string][]byte{
+		"123/123.lua":        []byte("addappid(123)\n"),
+		"123/456.lua":        []byte("addappid(456)\n"),
+		"123/1_2.manifest":   []byte("manifest"),
+		"123/depots/":        nil,
+		"123/depots/3.txt":   []byte("depot"),
+		"readme-not-lua.txt": []byte("readme"),
+	}))
+	dest := filepath.Join(t.TempDir(), "123")
+
+	files, err := ExtractArchive(context.Background(), path, dest, "123")
+	if err != nil {
+		t.Fatalf("ExtractArchive: %v", err)
+	}
+	want := []string{"123/1_2.manifest", "123/456.lua", "123/depots/3.txt", "readme-not-lua.txt"}
+	if strings.Join(sortedCopy(files), ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "123", "123.lua")); !os.IsNotExist(err) {
+		t.Fatalf("<appid>.lua should be left to the normal pipeline, stat err = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "123", "depots", "3.txt")); err != nil || string(data) != "depot" {
+		t.Fatalf("depots/3.txt = %q, %v", data, err)
+	}
+}
+
+// 排序后的副本
+func sortedCopy(items []string) []string {
+	sorted := append([]string(nil), items...)
+	sort.Strings(sorted)
+	return sorted
+}